@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/echocrow/cleardir/internal/audit"
+	"github.com/echocrow/cleardir/internal/humanize"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	os "github.com/echocrow/osa"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func addAuditFlag(fs *pflag.FlagSet, path *string) {
+	fs.StringVarP(path, "audit-log", "", "", flushHeredoc(`
+		append a record of every removal to this JSON lines
+		file; overrides the "[audit]" log of the config
+	`))
+}
+
+// resolveAuditPath returns the path of the audit log, preferring flag over
+// the config.
+func resolveAuditPath(flag string, cfg cleardir.Config) (string, error) {
+	if flag != "" {
+		return flag, nil
+	}
+	return audit.ParsePath(cfg)
+}
+
+// auditLog records the removals of a single run to an audit log.
+//
+// A nil auditLog records nothing.
+type auditLog struct {
+	w       *audit.Writer
+	ctx     audit.Context
+	job     string
+	roots   []string
+	matches map[string]auditMatch
+	// err is the first error writing the log.
+	err error
+}
+
+// auditMatch is a planned match along with its root.
+type auditMatch struct {
+	root string
+	m    cleardir.Match
+}
+
+// openAuditLog opens the audit log at path for a run of roots, or returns nil
+// if path is empty.
+func openAuditLog(path, job string, roots []string) (*auditLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	w, err := audit.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("audit log: %w", err)
+	}
+	return &auditLog{
+		w:       w,
+		ctx:     audit.NewContext(),
+		job:     job,
+		roots:   roots,
+		matches: map[string]auditMatch{},
+	}, nil
+}
+
+// plan notes the details of all planned matches, to record them on removal.
+func (a *auditLog) plan(plans []rootPlan) {
+	if a == nil {
+		return
+	}
+	for _, p := range plans {
+		for _, m := range p.matches {
+			a.matches[m.Path] = auditMatch{p.root, m}
+		}
+	}
+}
+
+func (a *auditLog) write(r audit.Record) {
+	r.Context = a.ctx
+	r.Job = a.job
+	if err := a.w.Write(r); err != nil && a.err == nil {
+		a.err = err
+	}
+}
+
+// removal records the removal of path.
+func (a *auditLog) removal(path string, err error) {
+	if a == nil {
+		return
+	}
+	am := a.matches[path]
+	r := audit.Record{
+		Event:  audit.EventRemove,
+		Root:   am.root,
+		Path:   path,
+		Kind:   am.m.Kind.String(),
+		Rule:   am.m.Rule,
+		Size:   am.m.Size,
+		Status: audit.StatusOK,
+	}
+	if err != nil {
+		r.Status, r.Error = audit.StatusFailed, err.Error()
+	}
+	a.write(r)
+}
+
+// finish records the outcome of the run, and closes the log. It returns any
+// error writing the log.
+func (a *auditLog) finish(sum runSummary, err error) error {
+	if a == nil {
+		return nil
+	}
+	r := audit.Record{
+		Event:  audit.EventRun,
+		Roots:  a.roots,
+		Count:  sum.removed,
+		Size:   sum.freedBytes,
+		Status: audit.StatusOK,
+	}
+	switch {
+	case err == errAborted:
+		r.Status = audit.StatusAborted
+	case err != nil:
+		r.Status, r.Error = audit.StatusFailed, err.Error()
+	}
+	a.write(r)
+	if cerr := a.w.Close(); a.err == nil {
+		a.err = cerr
+	}
+	if a.err != nil {
+		return fmt.Errorf("audit log: %w", a.err)
+	}
+	return nil
+}
+
+type logCmd struct {
+	cmd  *cobra.Command
+	opts logOpts
+}
+
+type logOpts struct {
+	cfg       string
+	auditPath string
+	since     string
+	until     string
+	path      string
+	json      bool
+}
+
+func newLogCmd() *logCmd {
+	lc := &logCmd{}
+	opts := &lc.opts
+
+	cmd := &cobra.Command{
+		Use:   "log",
+		Short: "Query the audit log of removals",
+		Long: heredoc.Doc(`
+			Log lists the records of the audit log, i.e. every removal along with
+			the user, host, and rule behind it, and the outcome of every run. The
+			log is configured via the "log" of the "[audit]" config section.
+		`),
+		Example: indentHeredoc(`
+		  cleardir log --since 2024-03-01 --until 2024-03-31
+		  cleardir log --path /srv/data --json | jq -r .user
+		`),
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLog(cmd, opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.cfg, "config", "c", "", "specify the configuration file path")
+	cmd.Flags().StringVarP(&opts.auditPath, "audit-log", "", "", "read this audit log instead of the configured one")
+	cmd.Flags().StringVarP(&opts.since, "since", "", "", flushHeredoc(`
+		only list records at or after this date or RFC 3339
+		time
+	`))
+	cmd.Flags().StringVarP(&opts.until, "until", "", "", flushHeredoc(`
+		only list records before this RFC 3339 time, or up to
+		the end of this date
+	`))
+	cmd.Flags().StringVarP(&opts.path, "path", "", "", flushHeredoc(`
+		only list removals of this path or of paths inside it,
+		and runs of overlapping roots
+	`))
+	cmd.Flags().BoolVarP(&opts.json, "json", "", false, "print matching records as JSON lines")
+
+	lc.cmd = cmd
+	return lc
+}
+
+func runLog(cmd *cobra.Command, opts *logOpts) error {
+	cfg, _, err := cleardir.ReadConfig(opts.cfg)
+	if err != nil {
+		return err
+	}
+	path, err := resolveAuditPath(opts.auditPath, cfg)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return errors.New("no audit log configured; set \"log\" in the \"[audit]\" config section, or use \"--audit-log\"")
+	}
+	f := audit.Filter{}
+	if f.Since, err = parseLogTime(opts.since, false); err != nil {
+		return fmt.Errorf("invalid \"--since\": %w", err)
+	}
+	if f.Until, err = parseLogTime(opts.until, true); err != nil {
+		return fmt.Errorf("invalid \"--until\": %w", err)
+	}
+	if opts.path != "" {
+		if f.Path, err = filepath.Abs(opts.path); err != nil {
+			return err
+		}
+	}
+	cmd.SilenceUsage = true
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	out := cmd.OutOrStdout()
+	enc := newJSONEncoder(out, false)
+	return audit.Read(file, f, func(r audit.Record) error {
+		if opts.json {
+			return enc.Encode(r)
+		}
+		printLogRecord(out, r)
+		return nil
+	})
+}
+
+// parseLogTime parses an RFC 3339 time, or a local date. Dates denote their
+// start, or, if end is set, their end.
+func parseLogTime(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return t, fmt.Errorf("%q is neither a date like \"2006-01-02\" nor an RFC 3339 time", s)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// printLogRecord prints r as a single human-readable line.
+func printLogRecord(w io.Writer, r audit.Record) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s@%s", r.Time.Format(time.RFC3339), r.User, r.Host)
+	if r.Job != "" {
+		fmt.Fprintf(&b, " [%s]", r.Job)
+	}
+	switch r.Event {
+	case audit.EventRemove:
+		if r.Status == audit.StatusOK {
+			fmt.Fprintf(&b, " removed %s %s", r.Kind, r.Path)
+		} else {
+			fmt.Fprintf(&b, " failed to remove %s %s", r.Kind, r.Path)
+		}
+		if r.Rule != "" {
+			fmt.Fprintf(&b, " (rule %s, %s)", r.Rule, humanize.Bytes(r.Size))
+		} else {
+			fmt.Fprintf(&b, " (%s)", humanize.Bytes(r.Size))
+		}
+	case audit.EventRun:
+		fmt.Fprintf(&b, " run %s: removed %s (%s) from %s",
+			r.Status, plural(r.Count, "item"), humanize.Bytes(r.Size), strings.Join(r.Roots, ", "))
+	default:
+		fmt.Fprintf(&b, " %s %s", r.Event, r.Status)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&b, ": %s", r.Error)
+	}
+	fmt.Fprintln(w, b.String())
+}
@@ -0,0 +1,148 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	stdos "os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/echocrow/cleardir/internal/audit"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var auditTimeRe = regexp.MustCompile(`(?m)^\d{4}-\d\d-\d\dT\S+`)
+
+func TestCmdAuditLog(t *testing.T) {
+	// Use the real file system, as the audit log is locked while writing.
+	dir := t.TempDir()
+	err := fsd{
+		"a":    fsd{"x": nil, "e": fsd{}},
+		"b":    fsd{"keep": nil},
+		"keep": nil,
+	}.Write(dir)
+	require.NoError(t, err)
+	require.NoError(t, stdos.WriteFile(filepath.Join(dir, "b", "x"), []byte("12345"), 0o644))
+	logPath := filepath.Join(t.TempDir(), "log", "audit.jsonl")
+	cfgPath := filepath.Join(t.TempDir(), "cfg")
+	cfg := fmt.Sprintf("%s\nx\n[audit]\nlog = %s\n[job all]\nroots = %s\n[job limited]\nroots = %[3]s\nmax-delete = 1\n", cleardir.ConfigHeader, logPath, dir)
+	require.NoError(t, stdos.WriteFile(cfgPath, []byte(cfg), 0o644))
+
+	var out bytes.Buffer
+	run := func(args ...string) error {
+		out.Reset()
+		c := newCmd()
+		c.SetOut(&out)
+		c.SetErr(&out)
+		c.SetArgs(args)
+		return c.Execute()
+	}
+
+	// Dry runs are not recorded.
+	require.NoError(t, run("--dry", "-c", cfgPath, dir))
+	require.NoError(t, run("-y", "-c", cfgPath, dir))
+	require.NoError(t, stdos.WriteFile(filepath.Join(dir, "b", "x"), nil, 0o644))
+	require.NoError(t, run("jobs", "run", "all", "-c", cfgPath))
+
+	ctx := audit.NewContext()
+	normalize := func(s string) string {
+		s = strings.ReplaceAll(s, ctx.User+"@"+ctx.Host, "$USER@$HOST")
+		s = auditTimeRe.ReplaceAllString(s, "$$TIME")
+		return normalizeOutput(s, dir)
+	}
+
+	require.NoError(t, run("log", "-c", cfgPath))
+	assertGolden(t, "audit-log.txt", normalize(out.String()))
+
+	require.NoError(t, run("log", "-c", cfgPath, "--path", filepath.Join(dir, "b"), "--json"))
+	recs := []audit.Record{}
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r audit.Record
+		require.NoError(t, dec.Decode(&r))
+		recs = append(recs, r)
+	}
+	require.Len(t, recs, 4)
+	assert.Equal(t, audit.Record{
+		Time:    recs[0].Time,
+		Context: recs[0].Context,
+		Event:   audit.EventRemove,
+		Root:    dir,
+		Path:    filepath.Join(dir, "b", "x"),
+		Kind:    "file",
+		Rule:    "x",
+		Size:    5,
+		Status:  audit.StatusOK,
+	}, recs[0])
+	assert.Equal(t, ctx.User, recs[0].User)
+	assert.Equal(t, ctx.Host, recs[0].Host)
+	assert.NotEmpty(t, recs[0].Run)
+	assert.NotEqual(t, recs[0].Run, recs[2].Run)
+	assert.Equal(t, audit.EventRun, recs[1].Event)
+	assert.Equal(t, "all", recs[3].Job)
+
+	today := time.Now().Format("2006-01-02")
+	require.NoError(t, run("log", "-c", cfgPath, "--since", today, "--until", today))
+	assert.Equal(t, 7, strings.Count(out.String(), "\n"))
+	require.NoError(t, run("log", "-c", cfgPath, "--until", today+"T00:00:00Z", "--since", "2000-01-01"))
+	assert.Equal(t, "", out.String())
+
+	lastRecord := func() audit.Record {
+		f, err := stdos.Open(logPath)
+		require.NoError(t, err)
+		defer f.Close()
+		var last audit.Record
+		require.NoError(t, audit.Read(f, audit.Filter{}, func(r audit.Record) error {
+			last = r
+			return nil
+		}))
+		return last
+	}
+
+	// Failed and aborted runs are recorded as such.
+	require.NoError(t, fsd{"c1": fsd{"x": nil}, "c2": fsd{"x": nil}}.Write(dir))
+	assert.Error(t, run("jobs", "run", "limited", "-c", cfgPath))
+	rec := lastRecord()
+	assert.Equal(t, audit.EventRun, rec.Event)
+	assert.Equal(t, "limited", rec.Job)
+	assert.Equal(t, audit.StatusFailed, rec.Status)
+	assert.Contains(t, rec.Error, "limit")
+
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	require.NoError(t, run("plan", "-c", cfgPath, "--out", planPath, dir))
+	assert.Error(t, run("apply", "-c", cfgPath, planPath))
+	rec = lastRecord()
+	assert.Equal(t, audit.EventRun, rec.Event)
+	assert.Equal(t, audit.StatusAborted, rec.Status)
+	c := newCmd()
+	c.SetOut(io.Discard)
+	c.SetErr(io.Discard)
+	c.SetArgs([]string{"apply", "-y", "-c", cfgPath, planPath})
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, c.ExecuteContext(canceled))
+	rec = lastRecord()
+	assert.Equal(t, audit.StatusFailed, rec.Status)
+
+	// Runs fail if their removals cannot be recorded.
+	badCfgPath := filepath.Join(t.TempDir(), "cfg")
+	badCfg := fmt.Sprintf("%s\nx\n[audit]\nlog = %s\n", cleardir.ConfigHeader, filepath.Join(cfgPath, "audit.jsonl"))
+	require.NoError(t, stdos.WriteFile(badCfgPath, []byte(badCfg), 0o644))
+	require.NoError(t, stdos.WriteFile(filepath.Join(dir, "x"), nil, 0o644))
+	err = run("-y", "-c", badCfgPath, dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "audit log:")
+	_, err = stdos.Stat(filepath.Join(dir, "x"))
+	assert.NoError(t, err)
+
+	assert.Error(t, run("log", "--audit-log", logPath, "--since", "yesterday"))
+	assert.Error(t, run("log", "-c", filepath.Join(dir, "keep")))
+}
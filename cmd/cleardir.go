@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"errors"
-	"path/filepath"
+	"fmt"
+	"io"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/echocrow/cleardir/internal/hooks"
 	"github.com/echocrow/cleardir/pkg/cleardir"
 	os "github.com/echocrow/osa"
 	"github.com/spf13/cobra"
@@ -16,18 +23,49 @@ type cleardirCmd struct {
 }
 
 type cleardirOpts struct {
-	cfg      string
-	maxDepth int
-	trivials []string
-	dry      bool
-	silent   bool
-	yes      bool
+	cfg          string
+	fromFile     string
+	maxDepth     int
+	trivials     []string
+	onError      string
+	jobs         int
+	sort         bool
+	output       string
+	print        bool
+	print0       bool
+	tree         bool
+	treeDepth    int
+	relative     string
+	absolute     bool
+	quote        string
+	color        string
+	stats        bool
+	noProgress   bool
+	dry          bool
+	silent       bool
+	yes          bool
+	interactive  bool
+	tui          bool
+	limits       limitOpts
+	lock         lockOpts
+	git          gitOpts
+	snapshotPath string
+	auditPath    string
 }
 
 const (
 	getCfgFlag = "?"
 )
 
+// errAborted reports a run that the user declined to proceed with.
+var errAborted = errors.New("Aborted")
+
+const (
+	onErrorAbort = "abort"
+	onErrorSkip  = "skip"
+	onErrorWarn  = "warn"
+)
+
 // NewCmd creates a new cleardir command.
 func NewCmd(version string) *cobra.Command {
 	cmd := newCleardirCmd().cmd
@@ -40,8 +78,10 @@ func NewCmd(version string) *cobra.Command {
 
 // Execute executes the root command.
 func Execute(version string) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 	cmd := NewCmd(version)
-	if err := cmd.Execute(); err != nil {
+	if err := cmd.ExecuteContext(ctx); err != nil {
 		os.Exit(1)
 	}
 }
@@ -51,7 +91,7 @@ func newCleardirCmd() *cleardirCmd {
 	opts := &root.opts
 
 	cmd := &cobra.Command{
-		Use:   "cleardir [PATH]",
+		Use:   "cleardir [PATH...]",
 		Short: "Clear empty directories",
 		Long: heredoc.Doc(`
 			Cleardir finds and deletes empty folders. Folders are considered empty
@@ -61,46 +101,106 @@ func newCleardirCmd() *cleardirCmd {
 		Example: indentHeredoc(`
 		  cleardir
 		  cleardir some/other/path
+		  cleardir some/path some/other/path
+		  find . -name node_modules -print0 | cleardir --from-file - -y
 		  cleardir -y -s
 		  cleardir --dry
+		  cleardir --dry -0 | xargs -0 ls -ld
 		`),
-		Args: cobra.MaximumNArgs(1),
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: completeDirs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runCleardir(cmd, opts, args)
 		},
 	}
 
 	cmd.Flags().StringVarP(&opts.cfg, "config", "c", "", "specify the configuration file path")
+	cmd.Flags().StringVarP(&opts.fromFile, "from-file", "", "", flushHeredoc(`
+		read newline- or NUL-separated paths from a file;
+		use "-" to read from stdin
+	`))
 	cmd.Flags().StringSliceVarP(&opts.trivials, "files", "f", nil, "list files that can be deleted safely")
 	cmd.Flags().IntVarP(&opts.maxDepth, "max-depth", "d", -1, flushHeredoc(`
 		limit how many sub-directories to descend to at most;
 		use "-1" for no limit
 	`))
+	cmd.Flags().StringVarP(&opts.onError, "on-error", "", onErrorAbort, flushHeredoc(`
+		handle unreadable directories: "abort" the scan, or "skip" or "warn"
+		and treat them as non-empty
+	`))
+	cmd.Flags().IntVarP(&opts.jobs, "jobs", "j", 1, "read or remove up to this many entries concurrently")
+	cmd.Flags().BoolVarP(&opts.sort, "sort", "", false, flushHeredoc(`
+		list matches in a deterministic order even when
+		reading directories concurrently
+	`))
+	cmd.Flags().StringVarP(&opts.output, "output", "o", outputText, flushHeredoc(`
+		print results as "text", a single "json" document,
+		or streamed "ndjson" objects
+	`))
+	cmd.Flags().BoolVarP(&opts.print, "print", "", false, "only print raw matched paths separated by newlines")
+	cmd.Flags().BoolVarP(&opts.print0, "print0", "0", false, "only print raw matched paths separated by NUL characters")
+	cmd.Flags().BoolVarP(&opts.tree, "tree", "t", false, "list matches as a tree relative to each root")
+	cmd.Flags().IntVarP(&opts.treeDepth, "tree-depth", "", -1, flushHeredoc(`
+		limit how many sub-directories to display in a tree;
+		use "-1" for no limit
+	`))
+	cmd.Flags().StringVarP(&opts.relative, "relative", "", "", flushHeredoc(`
+		display paths relative to each root;
+		use "--relative=cwd" for the working directory
+	`))
+	cmd.Flags().Lookup("relative").NoOptDefVal = relativeRoot
+	cmd.Flags().BoolVarP(&opts.absolute, "absolute", "", false, "display absolute paths (default)")
+	cmd.Flags().StringVarP(&opts.quote, "quote", "", quoteNone, flushHeredoc(`
+		quote displayed paths for "shell" or "c", or "none"
+	`))
+	cmd.Flags().StringVarP(&opts.color, "color", "", colorAuto, flushHeredoc(`
+		colorize output: "auto", "always", or "never";
+		"auto" respects NO_COLOR and only colorizes terminals
+	`))
+	cmd.Flags().BoolVarP(&opts.stats, "stats", "", false, flushHeredoc(`
+		also print summary statistics to stderr with
+		"--silent" or raw path output
+	`))
+	cmd.Flags().BoolVarP(&opts.noProgress, "no-progress", "", false, flushHeredoc(`
+		hide the progress line; progress is only shown
+		when stderr is a terminal
+	`))
 	cmd.Flags().BoolVarP(&opts.dry, "dry", "", false, "only list clearable files and directories")
 	cmd.Flags().BoolVarP(&opts.yes, "yes", "y", false, "skip and confirm prompts")
 	cmd.Flags().BoolVarP(&opts.silent, "silent", "s", false, "silence standard output; implies \"-y\"")
+	cmd.Flags().BoolVarP(&opts.interactive, "interactive", "i", false, "prompt for each top-level item before clearing it")
+	cmd.Flags().BoolVarP(&opts.tui, "tui", "", false, "browse and select clearable items in a full-screen terminal UI")
+	addLimitFlags(cmd.Flags(), &opts.limits)
+	addLockFlags(cmd.Flags(), &opts.lock)
+	addGitFlags(cmd.Flags(), &opts.git)
+	addSnapshotFlag(cmd.Flags(), &opts.snapshotPath)
+	addAuditFlag(cmd.Flags(), &opts.auditPath)
+	_ = cmd.RegisterFlagCompletionFunc("on-error", completeValues(onErrorAbort, onErrorSkip, onErrorWarn))
+	_ = cmd.RegisterFlagCompletionFunc("output", completeValues(outputText, outputJSON, outputNDJSON, outputPrint, outputPrint0, outputTree))
+	_ = cmd.RegisterFlagCompletionFunc("relative", completeValues(relativeRoot, relativeCwd))
+	_ = cmd.RegisterFlagCompletionFunc("quote", completeValues(quoteNone, quoteShell, quoteC))
+	_ = cmd.RegisterFlagCompletionFunc("color", completeValues(colorAuto, colorAlways, colorNever))
+	cmd.AddCommand(newExplainCmd().cmd)
+	cmd.AddCommand(newPlanCmd().cmd)
+	cmd.AddCommand(newApplyCmd().cmd)
+	cmd.AddCommand(newWatchCmd().cmd)
+	cmd.AddCommand(newDaemonCmd().cmd)
+	cmd.AddCommand(newJobsCmd().cmd)
+	cmd.AddCommand(newSystemdCmd().cmd)
+	cmd.AddCommand(newLogCmd().cmd)
+	cmd.AddCommand(newCompletionCmd().cmd)
 
 	root.cmd = cmd
 	return root
 }
 
-func runCleardir(cmd *cobra.Command, opts *cleardirOpts, args []string) error {
-	rawDir := ""
-	if len(args) >= 1 {
-		rawDir = args[0]
-	}
-
-	dir, err := filepath.Abs(rawDir)
-	if err != nil {
-		return err
-	}
-
+func runCleardir(cmd *cobra.Command, opts *cleardirOpts, args []string) (err error) {
 	getCfgPath := false
 	if opts.cfg == getCfgFlag {
 		getCfgPath = true
 		opts.cfg = ""
 	}
-	trivials, cfgPath, err := cleardir.ParseClearables(opts.cfg)
+	cfg, cfgPath, err := cleardir.ReadConfig(opts.cfg)
 	if err != nil {
 		return err
 	}
@@ -108,55 +208,359 @@ func runCleardir(cmd *cobra.Command, opts *cleardirOpts, args []string) error {
 		cmd.Println(cfgPath)
 		return nil
 	}
+	trivials := cfg.Clearables
+	limits, err := opts.limits.resolve(cmd.Flags(), cfg)
+	if err != nil {
+		return err
+	}
+	hks, err := hooks.Parse(cfg)
+	if err != nil {
+		return err
+	}
+	hks.Output = cmd.ErrOrStderr()
+	auditPath, err := resolveAuditPath(opts.auditPath, cfg)
+	if err != nil {
+		return err
+	}
+
+	noPrompt := opts.dry || opts.yes || opts.silent
+	if opts.interactive && noPrompt {
+		return errors.New("\"--interactive\" cannot be combined with \"--yes\", \"--silent\", or \"--dry\"")
+	}
+	if opts.tui {
+		if noPrompt || opts.interactive {
+			return errors.New("\"--tui\" cannot be combined with \"--interactive\", \"--yes\", \"--silent\", or \"--dry\"")
+		}
+		if _, _, err := tuiTerminal(cmd); err != nil {
+			return err
+		}
+	}
+	if opts.snapshotPath != "" && opts.dry {
+		return errors.New("\"--snapshot-report\" cannot be combined with \"--dry\"")
+	}
+	if opts.fromFile == stdinFlag && !noPrompt {
+		return errors.New("reading paths from stdin requires \"--yes\", \"--silent\", or \"--dry\"")
+	}
+	if err := applyOutputFlags(cmd, opts); err != nil {
+		return err
+	}
+	if !isOutputMode(opts.output) {
+		return fmt.Errorf("invalid output mode %q", opts.output)
+	}
+	if !isHumanOutput(opts.output) && !noPrompt {
+		return fmt.Errorf("%s output requires \"--yes\", \"--silent\", or \"--dry\"", opts.output)
+	}
+	locker, err := opts.lock.locker()
+	if err != nil {
+		return err
+	}
+	pf, err := newPathFormatter(opts, cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+	roots, err := resolveRoots(cmd, opts.fromFile, args)
+	if err != nil {
+		return err
+	}
 
 	trivials = append(trivials, opts.trivials...)
 
-	matches := make(chan string)
-	go func() {
-		err = cleardir.FindClearables(
-			matches,
-			dir,
-			trivials,
-			opts.maxDepth,
-		)
-		close(matches)
-	}()
-	dels := []string{}
-	for m := range matches {
-		if !opts.silent {
-			cmd.Printf("- %s\n", m)
+	sum := runSummary{dry: opts.dry}
+
+	var onError func(string, error) error
+	switch opts.onError {
+	case onErrorAbort:
+	case onErrorSkip, onErrorWarn:
+		onError = func(path string, err error) error {
+			sum.skipped = append(sum.skipped, skippedDir{path, err})
+			return nil
 		}
-		dels = append(dels, m)
+	default:
+		return fmt.Errorf("invalid on-error mode %q", opts.onError)
 	}
-	if err != nil {
+
+	// The progress line shares the terminal with any streamed matches, and is
+	// thus cleared before each match is printed.
+	var prog *progressLine
+	var onProgress func(cleardir.Progress)
+	if !opts.noProgress && isTerminal(cmd.ErrOrStderr()) {
+		prog = newProgressLine(cmd.ErrOrStderr())
+		onProgress = prog.scan
+	}
+	interleaved := !opts.silent && isTerminal(cmd.OutOrStdout())
+
+	findOpts := cleardir.Options{
+		Trivials:   trivials,
+		MaxDepth:   opts.maxDepth,
+		OnError:    onError,
+		Jobs:       opts.jobs,
+		Sort:       opts.sort,
+		OnProgress: onProgress,
+	}
+	if err := opts.git.apply(&findOpts, roots); err != nil {
+		cmd.SilenceUsage = true
 		return err
 	}
 
-	if len(dels) == 0 {
-		if !opts.silent {
-			cmd.Println("All clear!")
+	// Dry runs never remove anything, and thus need no lock.
+	if !opts.dry {
+		lk, err := lockRoots(cmd.Context(), locker, roots)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
 		}
-		return nil
-	} else {
-		if !opts.silent {
-			cmd.Printf("Can clear %d files.\n", len(dels))
+		defer lk.Unlock()
+	}
+
+	// Like locks, hooks only concern runs that may remove anything.
+	if !opts.dry {
+		defer func() {
+			if err == nil || err == errAborted {
+				return
+			}
+			env := hooks.Env{Roots: roots, Count: sum.removed, Bytes: sum.freedBytes, Err: err}
+			// Failed runs may have been interrupted, but still deserve a hook.
+			if herr := hks.Run(context.Background(), hooks.OnError, env, nil); herr != nil {
+				cmd.PrintErrf("Warning: %s.\n", herr)
+			}
+		}()
+		if err := hks.Run(cmd.Context(), hooks.PreScan, hooks.Env{Roots: roots}, nil); err != nil {
+			cmd.SilenceUsage = true
+			return err
 		}
 	}
 
-	if opts.dry {
+	// Audits likewise only record runs that may remove anything.
+	var aud *auditLog
+	if !opts.dry {
+		if aud, err = openAuditLog(auditPath, "", roots); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		defer func() {
+			if aerr := aud.finish(sum, err); aerr != nil && err == nil {
+				cmd.SilenceUsage = true
+				err = aerr
+			}
+		}()
+	}
+
+	finder := cleardir.NewFinder(findOpts)
+	rep := newReporter(cmd, opts, pf)
+	done := func() {
+		rep.done(sum)
+		// Text output includes statistics already.
+		if opts.stats && (opts.silent || !isHumanOutput(opts.output) && !embedsStats(opts.output)) {
+			printStats(cmd.ErrOrStderr(), sum.stats())
+		}
+	}
+
+	scanStart := time.Now()
+	sum.plans = make([]rootPlan, len(roots))
+	dels := []string{}
+	sizes := map[string]int64{}
+	for i, root := range roots {
+		plan := rootPlan{root: root, matches: []cleardir.Match{}}
+		matches, errc := finder.Find(cmd.Context(), root)
+		for m := range matches {
+			if interleaved {
+				prog.clear()
+			}
+			rep.match(root, m)
+			plan.matches = append(plan.matches, m)
+			sizes[m.Path] = m.Size
+		}
+		if err := <-errc; err != nil {
+			prog.clear()
+			return err
+		}
+		sum.plans[i] = plan
+		dels = append(dels, plan.paths()...)
+	}
+	sum.scanTime = time.Since(scanStart)
+	prog.clear()
+
+	if len(sum.skipped) > 0 {
+		printSkipped(cmd, sum.skipped, opts.onError == onErrorWarn)
+	}
+
+	rep.plan(sum.plans)
+	if len(dels) == 0 || opts.dry {
+		done()
 		return nil
 	}
 
-	ok := opts.silent || confirm(cmd, "Continue?", 1, opts.yes)
-	if !ok {
+	// Machine-readable output implies a prior "--yes" and must not be
+	// interrupted.
+	rawOut := !isHumanOutput(opts.output)
+
+	// Limits guard against large runs in every mode, unless forced. Selected
+	// items are checked once selected, and any other plan before prompting.
+	checkLimits := func(dels []string) error {
+		if err := opts.limits.check(limits, dels, sizes); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		return nil
+	}
+	selects := opts.tui || opts.interactive
+	if !selects {
+		if err := checkLimits(dels); err != nil {
+			return err
+		}
+	}
+
+	if opts.tui {
+		dels, err = runTUI(cmd, sum.plans)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		if len(dels) == 0 {
+			done()
+			return nil
+		}
+	} else if opts.interactive {
+		dels, err = selectInteractive(cmd, pf, sum.plans)
+		if err != nil {
+			cmd.SilenceUsage = true
+			if err == io.EOF {
+				return errAborted
+			}
+			return err
+		}
+		if len(dels) == 0 {
+			done()
+			return nil
+		}
+	} else if ok := opts.silent || rawOut || confirm(cmd, "Continue?", 1, opts.yes); !ok {
 		cmd.SilenceUsage = true
-		return errors.New("Aborted")
+		return errAborted
+	}
+	if selects {
+		if err := checkLimits(dels); err != nil {
+			return err
+		}
+	}
+
+	if hks.Has(hooks.PreRemove) {
+		if err := runPreRemoveHook(cmd.Context(), hks, hooks.Env{Roots: roots}, sum.plans, dels); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+	}
+
+	aud.plan(sum.plans)
+
+	// Post-remove hooks receive the same objects as NDJSON output.
+	var results bytes.Buffer
+	resultsRep := &ndjsonReporter{newJSONEncoder(&results, false)}
+	remover := cleardir.Remover{
+		Jobs: opts.jobs,
+		OnRemove: func(path string, err error) {
+			if err != nil {
+				sum.failed++
+			} else {
+				sum.removed++
+				sum.freedBytes += sizes[path]
+			}
+			if interleaved {
+				prog.clear()
+			}
+			rep.removal(path, err)
+			resultsRep.removal(path, err)
+			aud.removal(path, err)
+		},
+	}
+	if prog != nil {
+		remover.OnProgress = prog.remove
+	}
+	var before snapshot
+	if opts.snapshotPath != "" {
+		if before, err = takeSnapshot(roots); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+	}
+	removeStart := time.Now()
+	err = remover.Remove(cmd.Context(), dels...)
+	sum.removeTime = time.Since(removeStart)
+	prog.clear()
+	done()
+
+	if before != nil {
+		if serr := reportSnapshot(opts.snapshotPath, before, roots, dels); serr != nil && err == nil {
+			err = serr
+		}
+	}
+
+	resultsRep.done(sum)
+	env := hooks.Env{Roots: roots, Count: sum.removed, Bytes: sum.freedBytes}
+	if herr := hks.Run(cmd.Context(), hooks.PostRemove, env, results.Bytes()); herr != nil {
+		cmd.PrintErrf("Warning: %s.\n", herr)
 	}
 
-	err = cleardir.Remove(dels...)
 	if err != nil {
+		cmd.SilenceUsage = true
 		return err
 	}
 
 	return nil
 }
+
+// runPreRemoveHook runs the pre-remove hook with the matches of all paths to
+// remove as NDJSON, and fails if the hook vetoes their removal.
+func runPreRemoveHook(ctx context.Context, hks hooks.Hooks, env hooks.Env, plans []rootPlan, paths []string) error {
+	selected := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		selected[p] = true
+	}
+	var plan bytes.Buffer
+	planRep := &ndjsonReporter{newJSONEncoder(&plan, false)}
+	for _, p := range plans {
+		for _, m := range p.matches {
+			if selected[m.Path] {
+				planRep.match(p.root, m)
+				env.Count++
+				env.Bytes += m.Size
+			}
+		}
+	}
+	if err := hks.Run(ctx, hooks.PreRemove, env, plan.Bytes()); err != nil {
+		return fmt.Errorf("removal vetoed: %w", err)
+	}
+	return nil
+}
+
+// applyOutputFlags maps the output shorthand flags to output modes.
+func applyOutputFlags(cmd *cobra.Command, opts *cleardirOpts) error {
+	modes := []string{}
+	if opts.print {
+		modes = append(modes, outputPrint)
+	}
+	if opts.print0 {
+		modes = append(modes, outputPrint0)
+	}
+	if opts.tree {
+		modes = append(modes, outputTree)
+	}
+	if len(modes) == 0 {
+		return nil
+	}
+	if len(modes) > 1 || cmd.Flags().Changed("output") {
+		return errors.New("only one of \"--output\", \"--print\", \"--print0\", or \"--tree\" may be set")
+	}
+	opts.output = modes[0]
+	return nil
+}
+
+func printSkipped(cmd *cobra.Command, skipped []skippedDir, verbose bool) {
+	if !verbose {
+		cmd.PrintErrf("Warning: Skipped %d unreadable directories.\n", len(skipped))
+		return
+	}
+	cmd.PrintErrf("Warning: Skipped %d unreadable directories:\n", len(skipped))
+	for _, sk := range skipped {
+		cmd.PrintErrf("  %s\n", sk.err)
+	}
+}
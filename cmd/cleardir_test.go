@@ -1,14 +1,23 @@
 package cmd_test
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
+	"io"
+	stdos "os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/echocrow/cleardir/cmd"
+	"github.com/echocrow/cleardir/internal/ostest"
+	"github.com/echocrow/cleardir/pkg/cleardir"
 	"github.com/echocrow/fsnap/dirsnap"
+	"github.com/echocrow/osa"
 	"github.com/echocrow/osa/testos"
 	"github.com/echocrow/osa/vos"
 	"github.com/spf13/cobra"
@@ -22,6 +31,20 @@ var version = "0.0.0-test"
 
 var emptyRe = regexp.MustCompile(`^$`)
 
+var update = flag.Bool("update", false, "update golden files")
+
+func TestMain(m *testing.M) {
+	// Keep run locks of tests apart from any real runs.
+	runtimeDir, err := stdos.MkdirTemp("", "cleardir-test-runtime")
+	if err != nil {
+		panic(err)
+	}
+	stdos.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	code := m.Run()
+	stdos.RemoveAll(runtimeDir)
+	stdos.Exit(code)
+}
+
 func TestCmdBasicOut(t *testing.T) {
 	v, reset := vos.Patch()
 	defer reset()
@@ -133,6 +156,12 @@ func TestCmdExcute(t *testing.T) {
 			srcFsd,
 			"", nil,
 		},
+		{
+			"No Progress",
+			[]string{"-y", "--no-progress"}, "",
+			noEmptyDirFsd,
+			"", nil,
+		},
 		{
 			"Custom File",
 			[]string{"-f", "sf"}, "y\n",
@@ -226,9 +255,11 @@ func TestCmdListFiles(t *testing.T) {
 	tests := []struct {
 		name string
 		args []string
+		dry  bool
 	}{
-		{"Regular", nil},
-		{"Dry", []string{"--dry"}},
+		{"Regular", nil, false},
+		{"Dry", []string{"--dry"}, true},
+		{"Sorted Jobs", []string{"--jobs", "4", "--sort"}, false},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -239,6 +270,9 @@ func TestCmdListFiles(t *testing.T) {
 			stdin, stdout, stderr := vos.GetStdio(v)
 			stdin.Write([]byte("y\n"))
 
+			// Concurrent jobs must not modify the virtual file system at once.
+			defer osa.Patch(&lockedOS{I: v})()
+
 			err = execWithArgsInDir(dir, append(baseArgs, tc.args...)...)
 			require.NoError(t, err)
 			require.Empty(t, stderr)
@@ -248,6 +282,14 @@ func TestCmdListFiles(t *testing.T) {
 				wantLstRe += "- " + p + "\n"
 			}
 			assert.Regexp(t, wantLstRe, stdout, "expected output to list files")
+
+			wantFsd := fsd{"f": nil}
+			if tc.dry {
+				wantFsd = srcFsd
+			}
+			gotFsd, err := dirsnap.Read(dir, -1)
+			require.NoError(t, err)
+			assert.Equal(t, wantFsd, gotFsd)
 		})
 
 		vos.ClearStdio(v)
@@ -315,6 +357,330 @@ func TestCmdConfig(t *testing.T) {
 	}
 }
 
+func TestCmdOnError(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{
+		"d0": fsd{},
+		"d1": fsd{"bad": fsd{}},
+	}
+	skippedFsd := fsd{"d1": fsd{"bad": fsd{}}}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantFsd    fsd
+		wantErr    bool
+		wantStderr string
+	}{
+		{"Default", nil, srcFsd, true, "Error:.+unreadable"},
+		{"Abort", []string{"--on-error", "abort"}, srcFsd, true, "Error:.+unreadable"},
+		{"Skip", []string{"--on-error", "skip"}, skippedFsd, false,
+			`^Warning: Skipped 1 unreadable directories\.\n$`,
+		},
+		{"Warn", []string{"--on-error", "warn"}, skippedFsd, false,
+			`^Warning: Skipped 1 unreadable directories:\n  readdir .+/d1/bad: unreadable\n$`,
+		},
+		{"Invalid", []string{"--on-error", "foo"}, srcFsd, true, "Error: invalid on-error mode"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+
+			_, _, stderr := vos.GetStdio(v)
+
+			restore := ostest.PatchUnreadable(v, path.Join(dir, "d1", "bad"))
+			err = execWithArgsInDir(dir, append(tc.args, "-y")...)
+			restore()
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Regexp(t, tc.wantStderr, stderr)
+
+			gotFsd, fsdErr := dirsnap.Read(dir, -1)
+			require.NoError(t, fsdErr)
+			assert.Equal(t, tc.wantFsd, gotFsd)
+		})
+
+		vos.ClearStdio(v)
+	}
+}
+
+func TestCmdOutput(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{
+		"a": fsd{"d": fsd{}, "f": nil},
+		"b": fsd{"d": fsd{}, "keep": nil},
+		"c": fsd{"keep": nil},
+		"f": nil,
+	}
+
+	tests := []struct {
+		name   string
+		golden string
+		args   []string
+	}{
+		{"JSON Dry", "dry.json", []string{"-o", "json", "-f", "f", "--dry"}},
+		{"JSON", "run.json", []string{"-o", "json", "-f", "f", "-y"}},
+		{"JSON All Clear", "clear.json", []string{"-o", "json", "-y", "$ROOT/c"}},
+		{"NDJSON Dry", "dry.ndjson", []string{"-o", "ndjson", "-f", "f", "--dry"}},
+		{"NDJSON", "run.ndjson", []string{"-o", "ndjson", "-f", "f", "-y"}},
+		{"NDJSON Roots", "roots.ndjson", []string{"-o", "ndjson", "-f", "f", "-y", "$ROOT/a", "$ROOT/b"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+			testos.RequireWrite(t, v, path.Join(dir, "a", "f"), "sized")
+
+			_, stdout, stderr := vos.GetStdio(v)
+
+			args := []string{"--sort"}
+			hasRoot := false
+			for _, a := range tc.args {
+				if strings.HasPrefix(a, "$ROOT") {
+					a = strings.Replace(a, "$ROOT", dir, 1)
+					hasRoot = true
+				}
+				args = append(args, a)
+			}
+			if !hasRoot {
+				args = append(args, dir)
+			}
+
+			err = execWithArgs(args...)
+			require.NoError(t, err)
+			assert.Empty(t, stderr)
+
+			out, err := io.ReadAll(stdout)
+			require.NoError(t, err)
+			got := normalizeOutput(string(out), dir)
+			assertGolden(t, tc.golden, got)
+		})
+
+		vos.ClearStdio(v)
+	}
+}
+
+func TestCmdOutputRequiresNoPrompt(t *testing.T) {
+	_, reset := vos.Patch()
+	defer reset()
+
+	for _, o := range []string{"json", "ndjson"} {
+		err := execWithArgs("--output", o)
+		assert.Error(t, err)
+	}
+	err := execWithArgs("--output", "foo", "--dry")
+	assert.Error(t, err)
+}
+
+func TestCmdHooks(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{"a": fsd{"x": nil}, "e": fsd{}, "keep": nil}
+	tests := []struct {
+		name       string
+		args       []string
+		hooks      string
+		wantFsd    fsd
+		wantErr    string
+		wantStderr []string
+		notStderr  []string
+	}{
+		{
+			"Post Remove", []string{"-y"},
+			`post-remove = echo "$CLEARDIR_HOOK count=$CLEARDIR_COUNT"; cat`,
+			fsd{"keep": nil},
+			"",
+			[]string{"post-remove count=3\n", `"type":"removal"`, `"type":"summary"`},
+			nil,
+		},
+		{
+			"Pre Remove", []string{"-y"},
+			`pre-remove = echo "$CLEARDIR_HOOK count=$CLEARDIR_COUNT"; cat`,
+			fsd{"keep": nil},
+			"",
+			[]string{"pre-remove count=3\n", `"type":"match"`},
+			nil,
+		},
+		{
+			"Pre Remove Veto", []string{"-y"},
+			"pre-remove = exit 1",
+			srcFsd,
+			"removal vetoed: pre-remove hook failed: exit status 1",
+			nil,
+			nil,
+		},
+		{
+			"Pre Scan Fail", []string{"-y"},
+			"pre-scan = exit 2\non-error = echo \"error=$CLEARDIR_ERROR\"",
+			srcFsd,
+			"pre-scan hook failed: exit status 2",
+			[]string{"error=pre-scan hook failed: exit status 2\n"},
+			nil,
+		},
+		{
+			"Dry", []string{"--dry"},
+			"pre-scan = exit 1\npre-remove = exit 1\non-error = exit 1",
+			srcFsd,
+			"",
+			nil,
+			nil,
+		},
+		{
+			"Job", []string{"jobs", "run", "all"},
+			`post-remove = echo "$CLEARDIR_HOOK job=$CLEARDIR_JOB count=$CLEARDIR_COUNT"`,
+			fsd{"keep": nil},
+			"",
+			[]string{"post-remove job=all count=3\n"},
+			nil,
+		},
+		{
+			"Job Veto", []string{"jobs", "run", "all"},
+			"pre-remove = exit 1\non-error = echo \"$CLEARDIR_HOOK job=$CLEARDIR_JOB\"",
+			srcFsd,
+			"removal vetoed: pre-remove hook failed: exit status 1",
+			[]string{"on-error job=all\n"},
+			nil,
+		},
+		{
+			"Unknown Hook", []string{"-y"},
+			"post-scan = true",
+			srcFsd,
+			"unknown hook",
+			nil,
+			nil,
+		},
+		{
+			"Invalid Flags", []string{"-y", "--on-error", "nope"},
+			"pre-scan = echo hook ran\non-error = echo hook ran",
+			srcFsd,
+			"invalid on-error mode",
+			nil,
+			[]string{"hook ran"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+
+			cfgPath := path.Join(vos.MkTempDir(v), "cfg")
+			cfg := cleardir.ConfigHeader + "\nx\n[job all]\nroots = " + dir + "\n[hooks]\n" + tc.hooks
+			testos.RequireWrite(t, v, cfgPath, cfg)
+
+			_, _, stderr := vos.GetStdio(v)
+
+			args := append(tc.args, "-c", cfgPath)
+			if tc.args[0] != "jobs" {
+				args = append(args, dir)
+			}
+			err = execWithArgs(args...)
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			got, err := io.ReadAll(stderr)
+			require.NoError(t, err)
+			for _, want := range tc.wantStderr {
+				assert.Contains(t, string(got), want)
+			}
+			for _, notWant := range tc.notStderr {
+				assert.NotContains(t, string(got), notWant)
+			}
+
+			gotFsd, err := dirsnap.Read(dir, -1)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantFsd, gotFsd)
+		})
+
+		vos.ClearStdio(v)
+	}
+}
+
+func TestCmdPrint(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	names := []string{"-dash", "new\nline", "sp ace", "\xff\xfe"}
+	srcFsd := fsd{"keep": nil}
+	for _, n := range names {
+		srcFsd[n] = fsd{}
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		sep  string
+		want fsd
+	}{
+		{"Print Dry", []string{"--print", "--dry"}, "\n", srcFsd},
+		{"Print", []string{"--print", "-y"}, "\n", fsd{"keep": nil}},
+		{"Print0 Dry", []string{"--print0", "--dry"}, "\x00", srcFsd},
+		{"Print0 Short", []string{"-0", "--dry"}, "\x00", srcFsd},
+		{"Print0", []string{"-0", "-y"}, "\x00", fsd{"keep": nil}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+
+			_, stdout, stderr := vos.GetStdio(v)
+
+			err = execWithArgsInDir(dir, tc.args...)
+			require.NoError(t, err)
+			assert.Empty(t, stderr)
+
+			want := ""
+			for _, p := range joinBaseDir(dir, names) {
+				want += p + tc.sep
+			}
+			got, err := io.ReadAll(stdout)
+			require.NoError(t, err)
+			assert.Equal(t, want, string(got))
+
+			gotFsd, fsdErr := dirsnap.Read(dir, -1)
+			require.NoError(t, fsdErr)
+			assert.Equal(t, tc.want, gotFsd)
+		})
+
+		vos.ClearStdio(v)
+	}
+}
+
+func TestCmdPrintErr(t *testing.T) {
+	_, reset := vos.Patch()
+	defer reset()
+
+	tests := [][]string{
+		{"--print"},
+		{"--print0"},
+		{"--print", "--print0", "--dry"},
+		{"--print", "--output", "json", "--dry"},
+		{"--tree", "--print0", "--dry"},
+	}
+	for i, args := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			err := execWithArgs(args...)
+			assert.Error(t, err)
+		})
+	}
+}
+
 func TestExecute(t *testing.T) {
 	v, reset := vos.Patch()
 	defer reset()
@@ -361,6 +727,52 @@ func TestExecuteErr(t *testing.T) {
 	assert.Equal(t, wantExit, gotExit)
 }
 
+var (
+	secondsRe  = regexp.MustCompile(`("\w+_seconds": ?)[0-9.e-]+`)
+	durationRe = regexp.MustCompile(`(time: +)\S+`)
+	logTimeRe  = regexp.MustCompile(`("(?:time|next|duration)":)"[^"]*"`)
+)
+
+// normalizeOutput replaces volatile parts of the output such as the test dir
+// and durations with placeholders.
+func normalizeOutput(out, dir string) string {
+	out = strings.ReplaceAll(out, dir, "$ROOT")
+	out = secondsRe.ReplaceAllString(out, "${1}0")
+	out = logTimeRe.ReplaceAllString(out, `${1}"0"`)
+	return durationRe.ReplaceAllString(out, "${1}0s")
+}
+
+// assertGolden asserts that got matches the contents of a golden file in the
+// testdata directory.
+func assertGolden(t *testing.T, name string, got string) bool {
+	golden := filepath.Join("testdata", name)
+	if *update {
+		err := stdos.WriteFile(golden, []byte(got), 0644)
+		require.NoError(t, err)
+	}
+	want, err := stdos.ReadFile(golden)
+	require.NoError(t, err)
+	return assert.Equal(t, string(want), got)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
 func newCmd() *cobra.Command {
 	return cmd.NewCmd(version)
 }
@@ -377,6 +789,31 @@ func execWithArgsInDir(dir string, args ...string) error {
 	return c.Execute()
 }
 
+// lockedOS wraps an OS abstraction that is not safe for concurrent use, and
+// serializes access to its directories.
+type lockedOS struct {
+	osa.I
+	mu sync.Mutex
+}
+
+func (o *lockedOS) ReadDir(name string) ([]osa.DirEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.I.ReadDir(name)
+}
+
+func (o *lockedOS) Stat(name string) (osa.FileInfo, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.I.Stat(name)
+}
+
+func (o *lockedOS) Remove(name string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.I.Remove(name)
+}
+
 func joinBaseDir(root string, names []string) []string {
 	out := make([]string, len(names))
 	for i, p := range names {
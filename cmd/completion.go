@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/spf13/cobra"
+)
+
+const (
+	shellBash       = "bash"
+	shellZsh        = "zsh"
+	shellFish       = "fish"
+	shellPowerShell = "powershell"
+)
+
+type completionCmd struct {
+	cmd *cobra.Command
+}
+
+func newCompletionCmd() *completionCmd {
+	cc := &completionCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Generate a shell completion script",
+		Long: heredoc.Doc(`
+			Completion prints a script completing subcommands, flags, flag values,
+			job names, and directories for the given shell.
+		`),
+		Example: indentHeredoc(`
+		  source <(cleardir completion bash)
+		  cleardir completion zsh > "${fpath[1]}/_cleardir"
+		  cleardir completion fish > ~/.config/fish/completions/cleardir.fish
+		  cleardir completion powershell | Out-String | Invoke-Expression
+		`),
+		ValidArgs: []string{shellBash, shellZsh, shellFish, shellPowerShell},
+		Args:      cobra.ExactValidArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, out := cmd.Root(), cmd.OutOrStdout()
+			switch args[0] {
+			case shellBash:
+				return root.GenBashCompletionV2(out, true)
+			case shellZsh:
+				return root.GenZshCompletion(out)
+			case shellFish:
+				return root.GenFishCompletion(out, true)
+			case shellPowerShell:
+				return root.GenPowerShellCompletionWithDesc(out)
+			}
+			return fmt.Errorf("unsupported shell %q", args[0])
+		},
+	}
+
+	cc.cmd = cmd
+	return cc
+}
+
+// completeDirs completes directories as path arguments.
+func completeDirs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return nil, cobra.ShellCompDirectiveFilterDirs
+}
+
+// completeDir completes a directory as the only path argument.
+func completeDir(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeDirs(cmd, args, toComplete)
+}
+
+// completeValues completes any of values, e.g. of a flag.
+func completeValues(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		comps := []string{}
+		for _, v := range values {
+			if strings.HasPrefix(v, toComplete) {
+				comps = append(comps, v)
+			}
+		}
+		return comps, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeJobs completes the name of a job of the config at *cfgPath as the
+// only argument or as a flag value, described by the roots of the job.
+// Invalid configs complete nothing.
+func completeJobs(cfgPath *string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		_, js, err := readJobs(*cfgPath)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		comps := []string{}
+		for _, j := range js {
+			if strings.HasPrefix(j.Name, toComplete) {
+				comps = append(comps, j.Name+"\t"+strings.Join(j.Roots, ", "))
+			}
+		}
+		return comps, cobra.ShellCompDirectiveNoFileComp
+	}
+}
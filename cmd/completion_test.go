@@ -0,0 +1,113 @@
+package cmd_test
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/echocrow/osa/testos"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdCompletion(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	cfgPath := path.Join(vos.MkTempDir(v), "cfg")
+	testos.RequireWrite(t, v, cfgPath, cleardir.ConfigHeader+"\n"+"x\n[job downloads]\nroots = /dl\n[job docs]\nroots = /a, /b\n[job tmp]\nroots = /tmp\n")
+	badCfgPath := path.Join(vos.MkTempDir(v), "cfg")
+	testos.RequireWrite(t, v, badCfgPath, cleardir.ConfigHeader+"\n"+"[job bad]\nroots = rel\n")
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			"Subcommands", []string{"j"},
+			[]string{"jobs\tList or run configured jobs", ":16"},
+		},
+		{
+			"Paths", []string{"/some/dir", ""},
+			[]string{":16"},
+		},
+		{
+			"Flag Values", []string{"--on-error", "s"},
+			[]string{"skip", ":4"},
+		},
+		{
+			"Output Values", []string{"-o", "nd"},
+			[]string{"ndjson", ":4"},
+		},
+		{
+			"Job Names", []string{"jobs", "run", "-c", cfgPath, "do"},
+			[]string{"downloads\t/dl", "docs\t/a, /b", ":4"},
+		},
+		{
+			"Job Names Complete", []string{"jobs", "run", "-c", cfgPath, "tmp", ""},
+			[]string{":4"},
+		},
+		{
+			"Job Names Invalid Config", []string{"jobs", "run", "-c", badCfgPath, ""},
+			[]string{":4"},
+		},
+		{
+			"Job Flag Invalid Config", []string{"systemd", "generate", "-c", badCfgPath, "--job", ""},
+			[]string{":4"},
+		},
+		{
+			"Job Flag", []string{"systemd", "generate", "-c", cfgPath, "--job", "t"},
+			[]string{"tmp\t/tmp", ":4"},
+		},
+		{
+			"Plan Paths", []string{"plan", "--out", "plan.json", ""},
+			[]string{":16"},
+		},
+		{
+			"Explain Path", []string{"explain", ""},
+			[]string{":16"},
+		},
+		{
+			"Explain Path Complete", []string{"explain", "/some/dir", ""},
+			[]string{":4"},
+		},
+		{
+			"No Args", []string{"jobs", "list", ""},
+			[]string{":4"},
+		},
+		{
+			"Shells", []string{"completion", ""},
+			[]string{"bash", "zsh", "fish", "powershell", ":4"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			c := newCmd()
+			c.SetOut(&out)
+			c.SetErr(io.Discard)
+			c.SetArgs(append([]string{"__complete"}, tc.args...))
+			require.NoError(t, c.Execute())
+			assert.Equal(t, tc.want, strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"))
+		})
+	}
+
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		t.Run("Script "+shell, func(t *testing.T) {
+			var out bytes.Buffer
+			c := newCmd()
+			c.SetOut(&out)
+			c.SetArgs([]string{"completion", shell})
+			require.NoError(t, c.Execute())
+			assert.Contains(t, out.String(), "__complete")
+		})
+	}
+
+	err := execWithArgs("completion", "tcsh")
+	assert.Error(t, err)
+}
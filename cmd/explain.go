@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/spf13/cobra"
+)
+
+type explainCmd struct {
+	cmd  *cobra.Command
+	opts explainOpts
+}
+
+type explainOpts struct {
+	cfg      string
+	root     string
+	maxDepth int
+	trivials []string
+	limit    int
+	git      gitOpts
+}
+
+func newExplainCmd() *explainCmd {
+	ec := &explainCmd{}
+	opts := &ec.opts
+
+	cmd := &cobra.Command{
+		Use:   "explain PATH",
+		Short: "Explain why a directory is or isn't clearable",
+		Long: heredoc.Doc(`
+			Explain scans a single directory and lists the entries that keep it from
+			being cleared, along with the rules that make its trivial files
+			deletable.
+		`),
+		Example: indentHeredoc(`
+		  cleardir explain some/path
+		  cleardir explain -d 2 --root . some/path
+		`),
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeDir,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExplain(cmd, opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.cfg, "config", "c", "", "specify the configuration file path")
+	cmd.Flags().StringVarP(&opts.root, "root", "", "", flushHeredoc(`
+		the root a regular run would scan; used to apply
+		"--max-depth" relative to it (default PATH)
+	`))
+	cmd.Flags().StringSliceVarP(&opts.trivials, "files", "f", nil, "list files that can be deleted safely")
+	cmd.Flags().IntVarP(&opts.maxDepth, "max-depth", "d", -1, flushHeredoc(`
+		limit how many sub-directories to descend to at most;
+		use "-1" for no limit
+	`))
+	cmd.Flags().IntVarP(&opts.limit, "limit", "n", 10, flushHeredoc(`
+		list at most this many entries per section;
+		use "-1" for no limit
+	`))
+	addGitFlags(cmd.Flags(), &opts.git)
+
+	ec.cmd = cmd
+	return ec
+}
+
+func runExplain(cmd *cobra.Command, opts *explainOpts, rawPath string) error {
+	cfg, _, err := cleardir.ReadConfig(opts.cfg)
+	if err != nil {
+		return err
+	}
+	trivials := append(cfg.Clearables, opts.trivials...)
+
+	dir, err := filepath.Abs(rawPath)
+	if err != nil {
+		return err
+	}
+
+	maxDepth := opts.maxDepth
+	if opts.root != "" {
+		root, err := filepath.Abs(opts.root)
+		if err != nil {
+			return err
+		}
+		if !cleardir.IsNested(root, dir) && root != dir {
+			return fmt.Errorf("%s is not located in root %s", dir, root)
+		}
+		depth := pathDepth(root, dir)
+		if maxDepth >= 0 && depth > maxDepth {
+			cmd.Printf("%s is not clearable.\n", dir)
+			cmd.Printf("It is %d directories below %s, beyond the max depth of %d.\n", depth, root, maxDepth)
+			return nil
+		}
+		if maxDepth >= 0 {
+			maxDepth -= depth
+		}
+	}
+
+	blockers := []cleardir.Blocker{}
+	findOpts := cleardir.Options{
+		Trivials: trivials,
+		MaxDepth: maxDepth,
+		OnError:  func(string, error) error { return nil },
+		OnBlock: func(b cleardir.Blocker) {
+			blockers = append(blockers, b)
+		},
+	}
+	if err := opts.git.apply(&findOpts, []string{dir}); err != nil {
+		return err
+	}
+	finder := cleardir.NewFinder(findOpts)
+	trivialFiles := []cleardir.Match{}
+	matches, errc := finder.Find(cmd.Context(), dir)
+	for m := range matches {
+		if m.Kind == cleardir.KindFile {
+			trivialFiles = append(trivialFiles, m)
+		}
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+
+	sort.Slice(blockers, func(i, j int) bool {
+		return blockers[i].Path < blockers[j].Path
+	})
+	pf := pathFormatter{relative: relativeRoot, quote: quoteNone}
+
+	if len(blockers) == 0 {
+		cmd.Printf("%s is clearable.\n", dir)
+	} else {
+		cmd.Printf("%s is not clearable.\n", dir)
+		cmd.Printf("Blockers (%d):\n", len(blockers))
+		n := limitCount(len(blockers), opts.limit)
+		for _, b := range blockers[:n] {
+			m := cleardir.Match{Path: b.Path, Kind: b.Kind}
+			cmd.Printf("  - %s (%s)\n", explainPath(pf, dir, m), b.Reason)
+		}
+		printMore(cmd, len(blockers)-n)
+	}
+
+	if len(trivialFiles) > 0 {
+		cmd.Printf("Deletable by rule (%d):\n", len(trivialFiles))
+		n := limitCount(len(trivialFiles), opts.limit)
+		for _, m := range trivialFiles[:n] {
+			cmd.Printf("  - %s (rule: %s)\n", explainPath(pf, dir, m), m.Rule)
+		}
+		printMore(cmd, len(trivialFiles)-n)
+	}
+
+	return nil
+}
+
+func explainPath(pf pathFormatter, dir string, m cleardir.Match) string {
+	p := pf.path(dir, m)
+	if m.Kind == cleardir.KindDir {
+		p += string(filepath.Separator)
+	}
+	return p
+}
+
+// pathDepth counts the directories between root and its nested path.
+func pathDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+func limitCount(n, limit int) int {
+	if limit >= 0 && n > limit {
+		return limit
+	}
+	return n
+}
+
+func printMore(cmd *cobra.Command, more int) {
+	if more > 0 {
+		cmd.Printf("  ... and %d more\n", more)
+	}
+}
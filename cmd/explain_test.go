@@ -0,0 +1,67 @@
+package cmd_test
+
+import (
+	"io"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/echocrow/cleardir/internal/ostest"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdExplain(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{
+		"a":   fsd{"keep": nil, "x": nil, "e": fsd{}},
+		"b":   fsd{"x": nil, "y": nil},
+		"bad": fsd{},
+		"c":   fsd{"sd": fsd{"deep": fsd{"keep": nil}}},
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"Blocked", []string{"$ROOT"}, "explain-blocked.txt", false},
+		{"Clearable", []string{"$ROOT/b"}, "explain-clearable.txt", false},
+		{"Limit", []string{"-n", "1", "$ROOT"}, "explain-limit.txt", false},
+		{"Depth", []string{"--root", "$ROOT", "-d", "1", "$ROOT/c"}, "explain-depth.txt", false},
+		{"Beyond Depth", []string{"--root", "$ROOT", "-d", "0", "$ROOT/c/sd"}, "explain-beyond-depth.txt", false},
+		{"Outside Root", []string{"--root", "$ROOT/a", "$ROOT/b"}, "", true},
+		{"Missing Path", nil, "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+			defer ostest.PatchUnreadable(v, path.Join(dir, "bad"))()
+
+			_, stdout, _ := vos.GetStdio(v)
+
+			args := []string{"explain", "-f", "x", "-f", "y"}
+			for _, a := range tc.args {
+				args = append(args, strings.ReplaceAll(a, "$ROOT", dir))
+			}
+			err = execWithArgs(args...)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(stdout)
+			require.NoError(t, err)
+			assertGolden(t, tc.want, normalizeOutput(string(got), dir))
+		})
+
+		vos.ClearStdio(v)
+	}
+}
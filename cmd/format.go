@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	stdos "os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	os "github.com/echocrow/osa"
+	"golang.org/x/term"
+)
+
+const (
+	relativeRoot = "root"
+	relativeCwd  = "cwd"
+)
+
+const (
+	quoteNone  = "none"
+	quoteShell = "shell"
+	quoteC     = "c"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+const (
+	ansiDir   = "\x1b[1;34m"
+	ansiReset = "\x1b[0m"
+)
+
+// pathFormatter formats paths for human-readable output.
+type pathFormatter struct {
+	// relative is either empty for absolute paths, or the base to make paths
+	// relative to.
+	relative string
+	cwd      string
+	quote    string
+	color    bool
+}
+
+func newPathFormatter(opts *cleardirOpts, out io.Writer) (pathFormatter, error) {
+	f := pathFormatter{
+		relative: opts.relative,
+		quote:    opts.quote,
+	}
+
+	if opts.absolute && opts.relative != "" {
+		return f, errors.New("\"--absolute\" and \"--relative\" are mutually exclusive")
+	}
+	switch f.relative {
+	case "", relativeRoot:
+	case relativeCwd:
+		cwd, err := stdos.Getwd()
+		if err != nil {
+			return f, err
+		}
+		f.cwd = cwd
+	default:
+		return f, fmt.Errorf("invalid relative base %q", f.relative)
+	}
+
+	switch f.quote {
+	case quoteNone, quoteShell, quoteC:
+	default:
+		return f, fmt.Errorf("invalid quoting style %q", f.quote)
+	}
+
+	switch opts.color {
+	case colorAlways:
+		f.color = true
+	case colorNever:
+	case colorAuto:
+		f.color = stdos.Getenv("NO_COLOR") == "" && isTerminal(out)
+	default:
+		return f, fmt.Errorf("invalid color mode %q", opts.color)
+	}
+
+	return f, nil
+}
+
+// isTerminal reports whether w writes to a terminal.
+func isTerminal(w io.Writer) bool {
+	f := termFile(w)
+	return f != nil && term.IsTerminal(int(f.Fd()))
+}
+
+// termFile returns the file behind the standard stream s, or nil if s is not
+// backed by a file.
+func termFile(s interface{}) *stdos.File {
+	switch s {
+	case os.Stdin:
+		return stdos.Stdin
+	case os.Stdout:
+		return stdos.Stdout
+	case os.Stderr:
+		return stdos.Stderr
+	}
+	f, _ := s.(*stdos.File)
+	return f
+}
+
+// path formats the path of a match found in root.
+func (f pathFormatter) path(root string, m cleardir.Match) string {
+	p := f.rel(root, m.Path)
+	return f.paint(f.quoted(p), m.Kind == cleardir.KindDir)
+}
+
+// root formats a scan root.
+func (f pathFormatter) root(root string) string {
+	p := root
+	if f.relative == relativeCwd {
+		p = f.rel("", root)
+	}
+	return f.paint(f.quoted(p), true)
+}
+
+// name formats a single file or directory name.
+func (f pathFormatter) name(name string, isDir bool) string {
+	return f.paint(f.quoted(name), isDir)
+}
+
+// rel makes path relative according to the formatter's relative base.
+func (f pathFormatter) rel(root, path string) string {
+	base := ""
+	switch f.relative {
+	case relativeRoot:
+		base = root
+	case relativeCwd:
+		base = f.cwd
+	}
+	if base == "" {
+		return path
+	}
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+func (f pathFormatter) quoted(s string) string {
+	switch f.quote {
+	case quoteShell:
+		return shellQuote(s)
+	case quoteC:
+		return cQuote(s)
+	}
+	return s
+}
+
+func (f pathFormatter) paint(s string, isDir bool) string {
+	if !f.color || !isDir {
+		return s
+	}
+	return ansiDir + s + ansiReset
+}
+
+// shellQuote quotes s for POSIX shells, if necessary.
+//
+// Strings with non-printable characters or invalid UTF-8 are quoted via ANSI-C
+// quoting, i.e. $'...', which is supported by bash, zsh, and ksh.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if isShellSafe(s) {
+		return s
+	}
+	if !isPrintable(s) {
+		return "$'" + escapeC(s, '\'') + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func isShellSafe(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		safe := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.IndexByte("_@%+=:,./-", c) >= 0
+		if !safe {
+			return false
+		}
+	}
+	return true
+}
+
+func isPrintable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// cQuote quotes s as a C string literal.
+func cQuote(s string) string {
+	return `"` + escapeC(s, '"') + `"`
+}
+
+// escapeC escapes s using C escape sequences, including the given quote
+// character. Control characters and invalid UTF-8 bytes are escaped as octal
+// sequences.
+func escapeC(s string, quote byte) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			fmt.Fprintf(&b, `\%03o`, s[i])
+		case r == '\\' || r == rune(quote):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\%03o`, r)
+		default:
+			b.WriteRune(r)
+		}
+		i += size
+	}
+	return b.String()
+}
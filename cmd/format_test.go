@@ -0,0 +1,126 @@
+package cmd_test
+
+import (
+	"fmt"
+	"io"
+	stdos "os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdFormat(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{
+		"d": fsd{
+			"it's":             fsd{},
+			"new\nline":        fsd{},
+			"plain":            nil,
+			"sp ace":           fsd{},
+			"tab\tback\\slash": fsd{},
+			"\xff\xfe":         fsd{},
+			"ünï":              fsd{},
+			"keep":             nil,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		golden string
+		args   []string
+	}{
+		{"Absolute", "format-absolute.txt", []string{"--absolute"}},
+		{"Relative", "format-relative.txt", []string{"--relative"}},
+		{"Relative Root", "format-relative.txt", []string{"--relative=root"}},
+		{"Quote None", "format-relative.txt", []string{"--relative", "--quote", "none"}},
+		{"Quote Shell", "format-shell.txt", []string{"--relative", "--quote", "shell"}},
+		{"Quote C", "format-c.txt", []string{"--relative", "--quote", "c"}},
+		{"Color", "format-color.txt", []string{"--relative", "--color", "always"}},
+		{"Color Tree", "format-color-tree.txt", []string{"--tree", "--color", "always"}},
+		{"Color Auto", "format-relative.txt", []string{"--relative", "--color", "auto"}},
+		{"Color Never", "format-relative.txt", []string{"--relative", "--color", "never"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+
+			_, stdout, stderr := vos.GetStdio(v)
+
+			args := append([]string{"--dry", "-f", "plain"}, tc.args...)
+			err = execWithArgsInDir(dir, args...)
+			require.NoError(t, err)
+			assert.Empty(t, stderr)
+
+			out, err := io.ReadAll(stdout)
+			require.NoError(t, err)
+			got := normalizeOutput(string(out), dir)
+			assertGolden(t, tc.golden, got)
+		})
+
+		vos.ClearStdio(v)
+	}
+}
+
+func TestCmdFormatRelativeCwd(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	err := fsd{"d": fsd{}}.Write(dir)
+	require.NoError(t, err)
+
+	_, stdout, _ := vos.GetStdio(v)
+
+	err = execWithArgsInDir(dir, "--dry", "--relative=cwd")
+	require.NoError(t, err)
+
+	cwd, err := stdos.Getwd()
+	require.NoError(t, err)
+	want, err := filepath.Rel(cwd, path.Join(dir, "d"))
+	require.NoError(t, err)
+	assert.Regexp(t, "^- "+regexp.QuoteMeta(want)+"\n", stdout)
+}
+
+func TestCmdFormatNoColor(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	t.Setenv("NO_COLOR", "1")
+
+	dir := vos.MkTempDir(v)
+	err := fsd{"d": fsd{}}.Write(dir)
+	require.NoError(t, err)
+
+	_, stdout, _ := vos.GetStdio(v)
+
+	err = execWithArgsInDir(dir, "--dry")
+	require.NoError(t, err)
+	assert.NotContains(t, fmt.Sprint(stdout), "\x1b[")
+}
+
+func TestCmdFormatErr(t *testing.T) {
+	_, reset := vos.Patch()
+	defer reset()
+
+	tests := [][]string{
+		{"--relative", "--absolute"},
+		{"--relative=foo"},
+		{"--quote", "foo"},
+		{"--color", "foo"},
+	}
+	for i, args := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			err := execWithArgs(append(args, "--dry")...)
+			assert.Error(t, err)
+		})
+	}
+}
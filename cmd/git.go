@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/echocrow/cleardir/internal/git"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/spf13/pflag"
+)
+
+// gitOpts configures how to treat git work trees.
+type gitOpts struct {
+	enabled     bool
+	ignoredOnly bool
+}
+
+func addGitFlags(fs *pflag.FlagSet, opts *gitOpts) {
+	fs.BoolVarP(&opts.enabled, "git", "", false, flushHeredoc(`
+		keep anything tracked by git, and never enter
+		".git" directories
+	`))
+	fs.BoolVarP(&opts.ignoredOnly, "git-ignored-only", "", false, flushHeredoc(`
+		inside git work trees, only clear entries ignored
+		by git; implies "--git"
+	`))
+}
+
+// apply protects the contents of any git work trees of roots via opts.
+func (o gitOpts) apply(opts *cleardir.Options, roots []string) error {
+	if !o.enabled && !o.ignoredOnly {
+		return nil
+	}
+	f, err := git.NewFilter(roots, o.ignoredOnly)
+	if err != nil {
+		return err
+	}
+	opts.Keep = f.Keep
+	opts.Skip = f.Skip
+	return nil
+}
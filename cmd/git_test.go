@@ -0,0 +1,100 @@
+package cmd_test
+
+import (
+	"bytes"
+	"io"
+	stdos "os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/echocrow/fsnap/dirsnap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	// Keep any user config out of the test.
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	srcFsd := fsd{
+		".gitignore": nil,
+		"kept":       fsd{".gitkeep": nil},
+		"junk":       fsd{".DS_Store": nil},
+		"tmp":        fsd{".DS_Store": nil, "e": fsd{}},
+		"sub":        fsd{},
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		wantFsd fsd
+	}{
+		{
+			"Tracked", []string{"--git"},
+			fsd{".gitignore": nil, "kept": fsd{".gitkeep": nil}, "sub": fsd{}},
+		},
+		{
+			"Ignored Only", []string{"--git-ignored-only"},
+			fsd{".gitignore": nil, "kept": fsd{".gitkeep": nil}, "junk": fsd{".DS_Store": nil}, "sub": fsd{}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, srcFsd.Write(dir))
+			require.NoError(t, stdos.WriteFile(filepath.Join(dir, ".gitignore"), []byte("tmp/\n"), 0o644))
+			runGit(t, dir, "init", "-q")
+			runGit(t, dir, "add", ".gitignore", "kept")
+			runGit(t, dir, "update-index", "--add", "--cacheinfo", "160000,"+strings.Repeat("1", 40)+",sub")
+			cfgPath := filepath.Join(t.TempDir(), "cfg")
+			require.NoError(t, stdos.WriteFile(cfgPath, []byte(".DS_Store\n.gitkeep\n"), 0o644))
+
+			gitFsd, err := dirsnap.Read(filepath.Join(dir, ".git"), -1)
+			require.NoError(t, err)
+
+			c := newCmd()
+			c.SetOut(io.Discard)
+			c.SetErr(io.Discard)
+			c.SetArgs(append(tc.args, "-y", "-c", cfgPath, dir))
+			require.NoError(t, c.Execute())
+
+			gotFsd, err := dirsnap.Read(dir, -1)
+			require.NoError(t, err)
+			delete(gotFsd, ".git")
+			assert.Equal(t, tc.wantFsd, gotFsd)
+
+			// Empty directories of git itself are left alone.
+			gotGitFsd, err := dirsnap.Read(filepath.Join(dir, ".git"), -1)
+			require.NoError(t, err)
+			assert.Equal(t, gitFsd, gotGitFsd)
+		})
+	}
+
+	t.Run("Explain", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, fsd{"kept": fsd{".gitkeep": nil}}.Write(dir))
+		runGit(t, dir, "init", "-q")
+		runGit(t, dir, "add", "kept")
+
+		var out bytes.Buffer
+		c := newCmd()
+		c.SetOut(&out)
+		c.SetErr(&out)
+		c.SetArgs([]string{"explain", "--git", "-f", ".gitkeep", filepath.Join(dir, "kept")})
+		require.NoError(t, c.Execute())
+		assert.Contains(t, out.String(), "  - .gitkeep (protected)\n")
+	})
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	out, err := c.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/spf13/cobra"
+)
+
+const interactiveChoices = "y,n,a,q,?"
+
+const interactiveHelp = `y - clear this item
+n - keep this item
+a - clear this item and all remaining items
+q - keep this item and all remaining items
+? - print help
+`
+
+// selectItem is a top-level clearable match, grouped with all clearable
+// matches nested inside it.
+type selectItem struct {
+	root     string
+	match    cleardir.Match
+	children []cleardir.Match
+}
+
+// groupItems groups the matches of all plans into top-level items.
+//
+// Matches are expected in post-order, i.e. children before their parents.
+// Items keep the order of their top-level matches.
+func groupItems(plans []rootPlan) []selectItem {
+	items := []selectItem{}
+	for _, p := range plans {
+		isMatch := make(map[string]bool, len(p.matches))
+		for _, m := range p.matches {
+			isMatch[m.Path] = true
+		}
+		pending := []cleardir.Match{}
+		for _, m := range p.matches {
+			if isMatch[filepath.Dir(m.Path)] {
+				pending = append(pending, m)
+				continue
+			}
+			item := selectItem{root: p.root, match: m}
+			rest := pending[:0]
+			for _, c := range pending {
+				if cleardir.IsNested(m.Path, c.Path) {
+					item.children = append(item.children, c)
+				} else {
+					rest = append(rest, c)
+				}
+			}
+			pending = rest
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// selectInteractive asks which top-level items to clear and returns the paths
+// of all selected matches, in plan order.
+//
+// Items never contain one another, as nested matches are grouped into their
+// top-level item, and roots never overlap. Keeping an item thus never keeps
+// another one from being cleared.
+func selectInteractive(cmd *cobra.Command, pf pathFormatter, plans []rootPlan) ([]string, error) {
+	items := groupItems(plans)
+	keep := make([]bool, len(items))
+	p := newPrompter(cmd)
+
+	all, quit := false, false
+	for i, it := range items {
+		if all || quit {
+			keep[i] = quit
+			continue
+		}
+		msg := "Clear " + pf.path(it.root, it.match)
+		if len(it.children) > 0 {
+			dirs, files := 0, 0
+			for _, c := range it.children {
+				if c.Kind == cleardir.KindDir {
+					dirs++
+				} else {
+					files++
+				}
+			}
+			msg += " (with " + countsText(dirs, files) + ")"
+		}
+		msg += "?"
+
+		for {
+			res, err := p.ask(msg, interactiveChoices)
+			if err != nil {
+				return nil, err
+			}
+			switch res {
+			case "y", "yes":
+			case "n", "no":
+				keep[i] = true
+			case "a", "all":
+				all = true
+			case "q", "quit":
+				keep[i], quit = true, true
+			default:
+				cmd.Print(interactiveHelp)
+				continue
+			}
+			break
+		}
+	}
+
+	selected := make(map[string]bool)
+	for i, it := range items {
+		if keep[i] {
+			continue
+		}
+		selected[it.match.Path] = true
+		for _, c := range it.children {
+			selected[c.Path] = true
+		}
+	}
+
+	paths := []string{}
+	for _, plan := range plans {
+		for _, m := range plan.matches {
+			if selected[m.Path] {
+				paths = append(paths, m.Path)
+			}
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,98 @@
+package cmd_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/echocrow/fsnap/dirsnap"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdInteractive(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{
+		"a": fsd{"x": nil, "e": fsd{}},
+		"b": fsd{},
+		"c": fsd{"keep": nil, "d": fsd{}},
+		"x": nil,
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		sendIn  string
+		wantFsd fsd
+		wantOut string
+		wantErr bool
+	}{
+		{
+			"Yes", nil, "y\ny\ny\nyes\n",
+			fsd{"c": fsd{"keep": nil}},
+			"", false,
+		},
+		{
+			"Select", nil, "n\ny\n?\ny\nno\n",
+			fsd{"a": fsd{"x": nil, "e": fsd{}}, "c": fsd{"keep": nil}, "x": nil},
+			"interactive.txt", false,
+		},
+		{
+			"All", nil, "n\na\n",
+			fsd{"a": fsd{"x": nil, "e": fsd{}}, "c": fsd{"keep": nil}},
+			"", false,
+		},
+		{
+			"Quit", nil, "y\nq\n",
+			fsd{"b": fsd{}, "c": fsd{"keep": nil, "d": fsd{}}, "x": nil},
+			"", false,
+		},
+		{
+			"Keep All", nil, "q\n",
+			srcFsd,
+			"", false,
+		},
+		{
+			"EOF", nil, "y\n",
+			srcFsd,
+			"", true,
+		},
+		{
+			"With Yes", []string{"-y"}, "",
+			srcFsd,
+			"", true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+
+			stdin, stdout, _ := vos.GetStdio(v)
+			stdin.Write([]byte(tc.sendIn))
+
+			args := append([]string{"-i", "-f", "x"}, tc.args...)
+			err = execWithArgsInDir(dir, args...)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			gotFsd, fsdErr := dirsnap.Read(dir, -1)
+			require.NoError(t, fsdErr)
+			assert.Equal(t, tc.wantFsd, gotFsd)
+
+			if tc.wantOut != "" {
+				got, err := io.ReadAll(stdout)
+				require.NoError(t, err)
+				assertGolden(t, tc.wantOut, normalizeOutput(string(got), dir))
+			}
+		})
+
+		vos.ClearStdio(v)
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	levelInfo  = "info"
+	levelWarn  = "warn"
+	levelError = "error"
+)
+
+// jobLogger writes structured logs as JSON lines, one object per event.
+//
+// Each object starts with the "time", "level", and "msg" keys, followed by
+// any additional key-value pairs in the given order.
+type jobLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newJobLogger(w io.Writer) *jobLogger {
+	return &jobLogger{w: w}
+}
+
+// log writes a single event. kvs alternate between string keys and arbitrary
+// JSON-encodable values; errors are encoded as their message.
+func (l *jobLogger) log(level, msg string, kvs ...interface{}) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	writeLogField(&b, "time", time.Now().UTC().Format(time.RFC3339Nano))
+	b.WriteByte(',')
+	writeLogField(&b, "level", level)
+	b.WriteByte(',')
+	writeLogField(&b, "msg", msg)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		b.WriteByte(',')
+		writeLogField(&b, fmt.Sprint(kvs[i]), kvs[i+1])
+	}
+	b.WriteString("}\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.w.Write(b.Bytes())
+}
+
+func writeLogField(b *bytes.Buffer, key string, v interface{}) {
+	switch vv := v.(type) {
+	case error:
+		v = vv.Error()
+	case time.Duration:
+		v = vv.String()
+	}
+	k, _ := json.Marshal(key)
+	val, err := json.Marshal(v)
+	if err != nil {
+		val, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(k)
+	b.WriteByte(':')
+	b.Write(val)
+}
@@ -0,0 +1,358 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/echocrow/cleardir/internal/audit"
+	"github.com/echocrow/cleardir/internal/hooks"
+	"github.com/echocrow/cleardir/internal/jobs"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/spf13/cobra"
+)
+
+type jobsOpts struct {
+	cfg  string
+	lock lockOpts
+}
+
+type daemonCmd struct {
+	cmd  *cobra.Command
+	opts jobsOpts
+}
+
+func newDaemonCmd() *daemonCmd {
+	dc := &daemonCmd{}
+	opts := &dc.opts
+
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Run all configured jobs on their schedules",
+		Long: heredoc.Doc(`
+			Daemon runs all jobs of the configuration that have a schedule, and logs
+			each run as JSON lines. A job never runs twice at the same time; runs
+			that are due while the previous run is still in progress are skipped.
+			Daemon stops on SIGINT or SIGTERM, once all running jobs returned.
+		`),
+		Example: indentHeredoc(`
+		  cleardir daemon
+		  cleardir daemon -c /etc/cleardir/clearignore
+		`),
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDaemon(cmd, opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.cfg, "config", "c", "", "specify the configuration file path")
+
+	dc.cmd = cmd
+	return dc
+}
+
+type jobsCmd struct {
+	cmd  *cobra.Command
+	opts jobsOpts
+}
+
+func newJobsCmd() *jobsCmd {
+	jc := &jobsCmd{}
+	opts := &jc.opts
+
+	cmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "List or run configured jobs",
+		Long: heredoc.Doc(`
+			Jobs are configured via "[job NAME]" sections of the configuration file.
+		`),
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+	}
+	cmd.PersistentFlags().StringVarP(&opts.cfg, "config", "c", "", "specify the configuration file path")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:               "list",
+		Short:             "List all configured jobs",
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runJobsList(cmd, opts)
+		},
+	})
+	run := &cobra.Command{
+		Use:   "run NAME",
+		Short: "Run a single job once",
+		Example: indentHeredoc(`
+		  cleardir jobs run downloads
+		`),
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeJobs(&opts.cfg),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runJobsRun(cmd, opts, args[0])
+		},
+	}
+	addLockFlags(run.Flags(), &opts.lock)
+	cmd.AddCommand(run)
+
+	jc.cmd = cmd
+	return jc
+}
+
+func readJobs(cfgPath string) (cleardir.Config, []jobs.Job, error) {
+	cfg, path, err := cleardir.ReadConfig(cfgPath)
+	if err != nil {
+		return cfg, nil, err
+	}
+	js, err := jobs.Parse(cfg)
+	if err != nil {
+		return cfg, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, js, nil
+}
+
+func runJobsList(cmd *cobra.Command, opts *jobsOpts) error {
+	_, js, err := readJobs(opts.cfg)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	for _, j := range js {
+		schedule := j.RawSchedule
+		if schedule == "" {
+			schedule = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d root(s)\n", j.Name, schedule, j.Mode, len(j.Roots))
+	}
+	return tw.Flush()
+}
+
+func runJobsRun(cmd *cobra.Command, opts *jobsOpts, name string) error {
+	cfg, js, err := readJobs(opts.cfg)
+	if err != nil {
+		return err
+	}
+	j, ok := jobs.Find(js, name)
+	if !ok {
+		return fmt.Errorf("unknown job %q", name)
+	}
+	locker, err := opts.lock.locker()
+	if err != nil {
+		return err
+	}
+	r, err := newJobRunner(cmd, cfg, locker, newJobLogger(cmd.OutOrStdout()))
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	return r.run(cmd.Context(), j)
+}
+
+func runDaemon(cmd *cobra.Command, opts *jobsOpts) error {
+	cfg, js, err := readJobs(opts.cfg)
+	if err != nil {
+		return err
+	}
+	scheduled := []jobs.Job{}
+	for _, j := range js {
+		if j.Schedule != nil {
+			scheduled = append(scheduled, j)
+		}
+	}
+	if len(scheduled) == 0 {
+		return errors.New("no scheduled jobs configured")
+	}
+	log := newJobLogger(cmd.OutOrStdout())
+	// Runs on trees locked by other runs fail rather than pile up.
+	r, err := newJobRunner(cmd, cfg, cleardir.Locker{}, log)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	now := time.Now()
+	for _, j := range scheduled {
+		log.log(levelInfo, "job scheduled", "job", j.Name, "schedule", j.RawSchedule, "next", j.Schedule.Next(now))
+	}
+	s := &jobs.Scheduler{
+		Jobs: scheduled,
+		Run: func(ctx context.Context, j jobs.Job) {
+			// Failures are logged, and must not stop other jobs.
+			_ = r.run(ctx, j)
+		},
+		OnSkip: func(j jobs.Job) {
+			log.log(levelWarn, "job skipped", "job", j.Name, "reason", "previous run still in progress")
+		},
+	}
+	log.log(levelInfo, "daemon started", "jobs", len(scheduled))
+	err = s.Start(cmd.Context())
+	if errors.Is(err, context.Canceled) {
+		log.log(levelInfo, "daemon stopped")
+		return nil
+	}
+	return err
+}
+
+// jobRunner runs jobs, logging their progress and outcome.
+type jobRunner struct {
+	cfg    cleardir.Config
+	locker cleardir.Locker
+	hooks  hooks.Hooks
+	log    *jobLogger
+	// auditPath is the path of the audit log, if any.
+	auditPath string
+}
+
+func newJobRunner(cmd *cobra.Command, cfg cleardir.Config, l cleardir.Locker, log *jobLogger) (*jobRunner, error) {
+	hks, err := hooks.Parse(cfg)
+	if err != nil {
+		return nil, err
+	}
+	hks.Output = cmd.ErrOrStderr()
+	auditPath, err := audit.ParsePath(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &jobRunner{cfg: cfg, locker: l, hooks: hks, log: log, auditPath: auditPath}, nil
+}
+
+// run runs a single job once.
+func (r *jobRunner) run(ctx context.Context, j jobs.Job) error {
+	start := time.Now()
+	r.log.log(levelInfo, "job started", "job", j.Name, "mode", j.Mode, "roots", j.Roots)
+	res, err := r.clear(ctx, j, start)
+	if err != nil {
+		r.log.log(levelError, "job failed", "job", j.Name, "error", err, "duration", time.Since(start))
+		if j.Mode != jobs.ModeDry {
+			env := hooks.Env{Roots: j.Roots, Job: j.Name, Count: res.removed, Bytes: res.freedBytes, Err: err}
+			// Failed runs may have been interrupted, but still deserve a hook.
+			r.runHook(context.Background(), j, hooks.OnError, env, nil)
+		}
+	}
+	return err
+}
+
+// runHook runs a hook that cannot affect the run, and logs its failure.
+func (r *jobRunner) runHook(ctx context.Context, j jobs.Job, name string, env hooks.Env, stdin []byte) {
+	if err := r.hooks.Run(ctx, name, env, stdin); err != nil {
+		r.log.log(levelWarn, "hook failed", "job", j.Name, "hook", name, "error", err)
+	}
+}
+
+func (r *jobRunner) clear(ctx context.Context, j jobs.Job, start time.Time) (sum runSummary, err error) {
+	log := r.log
+	sum = runSummary{dry: j.Mode == jobs.ModeDry}
+	// Nested roots would otherwise be scanned, and removed from, twice.
+	roots := cleardir.DedupeRoots(j.Roots)
+	env := hooks.Env{Roots: roots, Job: j.Name}
+	var aud *auditLog
+	if !sum.dry {
+		var lk *cleardir.Lock
+		if lk, err = r.locker.Lock(ctx, roots...); err != nil {
+			return sum, err
+		}
+		defer lk.Unlock()
+
+		if aud, err = openAuditLog(r.auditPath, j.Name, roots); err != nil {
+			return sum, err
+		}
+		defer func() {
+			if aerr := aud.finish(sum, err); aerr != nil && err == nil {
+				err = aerr
+			}
+		}()
+
+		if err := r.hooks.Run(ctx, hooks.PreScan, env, nil); err != nil {
+			return sum, err
+		}
+	}
+
+	finder := cleardir.NewFinder(cleardir.Options{
+		Trivials: append(append([]string{}, r.cfg.Clearables...), j.Trivials...),
+		MaxDepth: j.MaxDepth,
+		OnError: func(path string, err error) error {
+			log.log(levelWarn, "skipped unreadable directory", "job", j.Name, "path", path, "error", err)
+			return nil
+		},
+	})
+
+	dels := []string{}
+	sizes := map[string]int64{}
+	delBytes := int64(0)
+	for _, root := range roots {
+		plan := rootPlan{root: root}
+		matches, errc := finder.Find(ctx, root)
+		for m := range matches {
+			plan.matches = append(plan.matches, m)
+			dels = append(dels, m.Path)
+			sizes[m.Path] = m.Size
+			delBytes += m.Size
+		}
+		if err := <-errc; err != nil {
+			return sum, err
+		}
+		sum.plans = append(sum.plans, plan)
+	}
+
+	limits := deleteLimits{items: j.MaxDelete, bytes: j.MaxDeleteSize}
+	if msg := limits.exceeded(len(dels), delBytes); msg != "" {
+		return sum, errors.New(msg)
+	}
+
+	if sum.dry {
+		for _, p := range dels {
+			log.log(levelInfo, "clearable", "job", j.Name, "path", p)
+		}
+		log.log(levelInfo, "job finished", "job", j.Name, "clearable", len(dels), "bytes", delBytes, "duration", time.Since(start))
+		return sum, nil
+	}
+
+	if r.hooks.Has(hooks.PreRemove) {
+		if err := runPreRemoveHook(ctx, r.hooks, env, sum.plans, dels); err != nil {
+			return sum, err
+		}
+	}
+
+	aud.plan(sum.plans)
+	var results bytes.Buffer
+	resultsRep := &ndjsonReporter{newJSONEncoder(&results, false)}
+	remover := cleardir.Remover{
+		OnRemove: func(path string, err error) {
+			resultsRep.removal(path, err)
+			aud.removal(path, err)
+			if err != nil {
+				sum.failed++
+				log.log(levelError, "remove failed", "job", j.Name, "path", path, "error", err)
+				return
+			}
+			sum.removed++
+			sum.freedBytes += sizes[path]
+			log.log(levelInfo, "removed", "job", j.Name, "path", path)
+		},
+	}
+	var before snapshot
+	if j.SnapshotReport != "" {
+		if before, err = takeSnapshot(roots); err != nil {
+			return sum, err
+		}
+	}
+	if err := remover.Remove(ctx, dels...); err != nil {
+		return sum, err
+	}
+	if before != nil {
+		if err := reportSnapshot(j.SnapshotReport, before, roots, dels); err != nil {
+			return sum, err
+		}
+	}
+	log.log(levelInfo, "job finished", "job", j.Name, "removed", sum.removed, "bytes", sum.freedBytes, "duration", time.Since(start))
+
+	resultsRep.done(sum)
+	env.Count, env.Bytes = sum.removed, sum.freedBytes
+	r.runHook(ctx, j, hooks.PostRemove, env, results.Bytes())
+	return sum, nil
+}
@@ -0,0 +1,167 @@
+package cmd_test
+
+import (
+	"context"
+	"io"
+	stdos "os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/echocrow/fsnap/dirsnap"
+	"github.com/echocrow/osa/testos"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdJobs(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{
+		"a": fsd{"keep": nil, "x": nil, "e": fsd{}},
+		"b": fsd{"x": nil, "y": nil},
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		job     string
+		want    string
+		wantFsd fsd
+		wantErr bool
+	}{
+		{
+			"Run", []string{"jobs", "run", "all"},
+			"roots = $ROOT/a, $ROOT/b\nfiles = y",
+			"jobs-run.txt",
+			fsd{"a": fsd{"keep": nil}, "b": fsd{}},
+			false,
+		},
+		{
+			"Run Dry", []string{"jobs", "run", "all"},
+			"roots = $ROOT/a, $ROOT/b\nmode = dry",
+			"jobs-run-dry.txt",
+			srcFsd,
+			false,
+		},
+		{
+			"Run Nested Roots", []string{"jobs", "run", "all"},
+			"roots = $ROOT/a, $ROOT/a/e, $ROOT/a/\nmode = dry",
+			"jobs-run-nested.txt",
+			srcFsd,
+			false,
+		},
+		{
+			"Run Limit", []string{"jobs", "run", "all"},
+			"roots = $ROOT/a, $ROOT/b\nfiles = y\nmax-delete = 2",
+			"jobs-run-limit.txt",
+			srcFsd,
+			true,
+		},
+		{
+			"List", []string{"jobs", "list"},
+			"roots = $ROOT/a, $ROOT/b\nschedule = @daily",
+			"jobs-list.txt",
+			srcFsd,
+			false,
+		},
+		{
+			"Unknown Job", []string{"jobs", "run", "other"},
+			"roots = $ROOT/a",
+			"",
+			srcFsd,
+			true,
+		},
+		{
+			"Invalid Job", []string{"jobs", "list"},
+			"roots = a",
+			"",
+			srcFsd,
+			true,
+		},
+		{
+			"Daemon Without Schedules", []string{"daemon"},
+			"roots = $ROOT/a",
+			"",
+			srcFsd,
+			true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+
+			cfgPath := path.Join(vos.MkTempDir(v), "cfg")
+			cfg := cleardir.ConfigHeader + "\n" + "x\n[job manual]\nroots = /srv\n[job all]\n" + strings.ReplaceAll(tc.job, "$ROOT", dir)
+			testos.RequireWrite(t, v, cfgPath, cfg)
+
+			_, stdout, _ := vos.GetStdio(v)
+
+			err = execWithArgs(append(tc.args, "-c", cfgPath)...)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if tc.want != "" {
+				got, err := io.ReadAll(stdout)
+				require.NoError(t, err)
+				assertGolden(t, tc.want, normalizeOutput(string(got), dir))
+			}
+
+			gotFsd, err := dirsnap.Read(dir, -1)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantFsd, gotFsd)
+		})
+
+		vos.ClearStdio(v)
+	}
+}
+
+func TestCmdDaemon(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "config")
+	root := filepath.Join(dir, "root")
+	require.NoError(t, stdos.MkdirAll(filepath.Join(root, "a"), 0o755))
+	require.NoError(t, stdos.WriteFile(cfg, []byte(
+		cleardir.ConfigHeader+"\n"+"[job often]\nroots = "+root+"\nschedule = @every 10ms\n",
+	), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := &syncBuffer{}
+	c := newCmd()
+	c.SetOut(out)
+	c.SetErr(out)
+	c.SetArgs([]string{"daemon", "-c", cfg})
+	errc := make(chan error, 1)
+	go func() { errc <- c.ExecuteContext(ctx) }()
+
+	isCleared := func(path string) func() bool {
+		return func() bool {
+			_, err := stdos.Stat(path)
+			return stdos.IsNotExist(err)
+		}
+	}
+	require.Eventually(t, isCleared(filepath.Join(root, "a")), time.Second, 5*time.Millisecond)
+	require.NoError(t, stdos.Mkdir(filepath.Join(root, "b"), 0o755))
+	require.Eventually(t, isCleared(filepath.Join(root, "b")), time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-errc)
+
+	got := out.String()
+	assert.Contains(t, got, `"msg":"job scheduled","job":"often","schedule":"@every 10ms"`)
+	assert.Contains(t, got, `"msg":"daemon started","jobs":1}`)
+	assert.Contains(t, got, `"msg":"removed","job":"often","path":"`+filepath.Join(root, "a")+`"}`)
+	assert.Contains(t, got, `"msg":"removed","job":"often","path":"`+filepath.Join(root, "b")+`"}`)
+	assert.Contains(t, got, `"msg":"daemon stopped"}`)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/echocrow/cleardir/internal/humanize"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/spf13/pflag"
+)
+
+const limitsSection = "limits"
+
+// deleteLimits caps how much a run may clear without "--force-large". Zero
+// values denote no limit.
+type deleteLimits struct {
+	items int
+	bytes int64
+}
+
+// limitOpts configures deletion limits via flags.
+type limitOpts struct {
+	maxDelete     int
+	maxDeleteSize string
+	forceLarge    bool
+}
+
+func addLimitFlags(fs *pflag.FlagSet, opts *limitOpts) {
+	fs.IntVarP(&opts.maxDelete, "max-delete", "", 0, flushHeredoc(`
+		refuse to clear more than this many items, even when
+		prompted; use "0" for no limit
+	`))
+	fs.StringVarP(&opts.maxDeleteSize, "max-delete-size", "", "", flushHeredoc(`
+		refuse to clear files larger than this in total, even
+		when prompted, e.g. "500MiB"
+	`))
+	fs.BoolVarP(&opts.forceLarge, "force-large", "", false, "clear even if a deletion limit is exceeded")
+}
+
+// resolve resolves the deletion limits from the flags of fs, falling back to
+// the "[limits]" section of the config.
+func (o limitOpts) resolve(fs *pflag.FlagSet, cfg cleardir.Config) (deleteLimits, error) {
+	l := deleteLimits{items: o.maxDelete}
+	rawSize := o.maxDeleteSize
+
+	if sec, ok := cfg.Section(limitsSection); ok {
+		if v, ok := sec.Values["max-delete"]; ok && !fs.Changed("max-delete") {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return l, fmt.Errorf("invalid max-delete %q in config", v)
+			}
+			l.items = n
+		}
+		if v, ok := sec.Values["max-delete-size"]; ok && !fs.Changed("max-delete-size") {
+			rawSize = v
+		}
+	}
+	if l.items < 0 {
+		return l, fmt.Errorf("invalid max-delete %d", l.items)
+	}
+
+	if rawSize != "" {
+		n, err := humanize.ParseBytes(rawSize)
+		if err != nil {
+			return l, fmt.Errorf("invalid max-delete-size: %w", err)
+		}
+		l.bytes = n
+	}
+	return l, nil
+}
+
+// check fails if paths exceed any limit, unless forced. sizes maps paths to
+// their sizes.
+func (o limitOpts) check(l deleteLimits, paths []string, sizes map[string]int64) error {
+	if o.forceLarge {
+		return nil
+	}
+	bytes := int64(0)
+	for _, p := range paths {
+		bytes += sizes[p]
+	}
+	if msg := l.exceeded(len(paths), bytes); msg != "" {
+		return fmt.Errorf("%s; use \"--force-large\" to proceed", msg)
+	}
+	return nil
+}
+
+// exceeded describes the first limit exceeded by the given plan size, or
+// returns an empty string.
+func (l deleteLimits) exceeded(items int, bytes int64) string {
+	if l.items > 0 && items > l.items {
+		return fmt.Sprintf("clearing %d items exceeds the limit of %d", items, l.items)
+	}
+	if l.bytes > 0 && bytes > l.bytes {
+		return fmt.Sprintf("clearing %s exceeds the limit of %s", humanize.Bytes(bytes), humanize.Bytes(l.bytes))
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package cmd_test
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/echocrow/fsnap/dirsnap"
+	"github.com/echocrow/osa/testos"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdMaxDelete(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{
+		"a": fsd{},
+		"b": fsd{},
+		"x": nil,
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		cfg        string
+		sendIn     string
+		wantClear  bool
+		wantStderr string
+	}{
+		{"Below", []string{"-y", "--max-delete", "3"}, "", "", true, ""},
+		{"Exceeded", []string{"-y", "--max-delete", "2"}, "", "", false, "exceeds the limit of 2"},
+		{"Exceeded Silent", []string{"-s", "--max-delete", "2"}, "", "", false, "exceeds the limit of 2"},
+		{"Exceeded JSON", []string{"-o", "json", "-y", "--max-delete", "2"}, "", "", false, "exceeds the limit of 2"},
+		{"Forced", []string{"-y", "--max-delete", "2", "--force-large"}, "", "", true, ""},
+		{"Prompted", []string{"--max-delete", "2"}, "", "y\n", false, "exceeds the limit of 2"},
+		{"Prompted Forced", []string{"--max-delete", "2", "--force-large"}, "", "y\n", true, ""},
+		{"Interactive", []string{"-i", "--max-delete", "2"}, "", "a\n", false, "exceeds the limit of 2"},
+		{"Size Below", []string{"-y", "--max-delete-size", "2KiB"}, "", "", true, ""},
+		{"Size Exceeded", []string{"-y", "--max-delete-size", "1k"}, "", "", false, "exceeds the limit of 1.0 KiB"},
+		{"Config", []string{"-y"}, "[limits]\nmax-delete = 2\n", "", false, "exceeds the limit of 2"},
+		{"Config Size", []string{"-y"}, "[limits]\nmax-delete-size = 1000\n", "", false, "exceeds the limit of 1000 B"},
+		{"Config Overridden", []string{"-y", "--max-delete", "0"}, "[limits]\nmax-delete = 2\n", "", true, ""},
+		{"Invalid Size", []string{"-y", "--max-delete-size", "lots"}, "", "", false, "invalid max-delete-size"},
+		{"Invalid Config", []string{"-y"}, "[limits]\nmax-delete = lots\n", "", false, "invalid max-delete"},
+	}
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+			testos.RequireWrite(t, v, path.Join(dir, "x"), strings.Repeat("x", 1500))
+
+			stdin, _, stderr := vos.GetStdio(v)
+			stdin.Write([]byte(tc.sendIn))
+
+			args := append([]string{"-f", "x"}, tc.args...)
+			if tc.cfg != "" {
+				cfgDir, err := v.UserConfigDir()
+				require.NoError(t, err)
+				cfgPath := path.Join(cfgDir, fmt.Sprintf("limits%d", i))
+				testos.RequireWrite(t, v, cfgPath, cleardir.ConfigHeader+"\n"+tc.cfg)
+				args = append(args, "-c", cfgPath)
+			}
+
+			err = execWithArgsInDir(dir, args...)
+			if tc.wantClear {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+
+			gotFsd, fsdErr := dirsnap.Read(dir, -1)
+			require.NoError(t, fsdErr)
+			if tc.wantClear {
+				assert.Equal(t, fsd{}, gotFsd)
+			} else {
+				assert.Equal(t, srcFsd, gotFsd)
+			}
+
+			if tc.wantStderr == "" {
+				assert.Regexp(t, emptyRe, stderr)
+			} else {
+				assert.Regexp(t, tc.wantStderr, stderr)
+			}
+		})
+
+		vos.ClearStdio(v)
+	}
+}
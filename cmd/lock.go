@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/spf13/pflag"
+)
+
+// lockOpts configures how to handle roots locked by other runs.
+type lockOpts struct {
+	wait   bool
+	noWait bool
+}
+
+func addLockFlags(fs *pflag.FlagSet, opts *lockOpts) {
+	fs.BoolVarP(&opts.wait, "wait", "", false, "wait for other runs on overlapping directories to finish")
+	fs.BoolVarP(&opts.noWait, "no-wait", "", false, "fail if another run on overlapping directories is in progress (default)")
+}
+
+func (o lockOpts) locker() (cleardir.Locker, error) {
+	if o.wait && o.noWait {
+		return cleardir.Locker{}, errors.New("\"--wait\" cannot be combined with \"--no-wait\"")
+	}
+	return cleardir.Locker{Wait: o.wait}, nil
+}
+
+// lockRoots locks roots against concurrent runs.
+func lockRoots(ctx context.Context, l cleardir.Locker, roots []string) (*cleardir.Lock, error) {
+	lk, err := l.Lock(ctx, roots...)
+	var le *cleardir.LockedError
+	if errors.As(err, &le) {
+		return nil, errors.New(le.Error() + "; use \"--wait\" to wait for it")
+	}
+	return lk, err
+}
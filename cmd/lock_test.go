@@ -0,0 +1,54 @@
+package cmd_test
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/echocrow/fsnap/dirsnap"
+	"github.com/echocrow/osa/testos"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdLock(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	err := fsd{"a": fsd{}, "b": fsd{"c": fsd{}}}.Write(dir)
+	require.NoError(t, err)
+	cfgPath := path.Join(vos.MkTempDir(v), "cfg")
+	testos.RequireWrite(t, v, cfgPath, cleardir.ConfigHeader+"\n"+"[job b]\nroots = "+path.Join(dir, "b"))
+
+	held, err := cleardir.Locker{}.Lock(context.Background(), path.Join(dir, "b"))
+	require.NoError(t, err)
+
+	err = execWithArgsInDir(dir, "-y")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is locked by another run")
+	assert.Contains(t, err.Error(), "--wait")
+
+	err = execWithArgs("jobs", "run", "-c", cfgPath, "b")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is locked by another run")
+
+	err = execWithArgsInDir(dir, "-y", "--wait", "--no-wait")
+	assert.Error(t, err)
+
+	err = execWithArgsInDir(dir, "--dry")
+	assert.NoError(t, err)
+	err = execWithArgsInDir(path.Join(dir, "a"), "-y")
+	assert.NoError(t, err)
+
+	require.NoError(t, held.Unlock())
+	err = execWithArgsInDir(dir, "-y", "--no-wait")
+	assert.NoError(t, err)
+	gotFsd, err := dirsnap.Read(dir, -1)
+	require.NoError(t, err)
+	assert.Equal(t, fsd{}, gotFsd)
+
+	vos.ClearStdio(v)
+}
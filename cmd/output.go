@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+	outputPrint  = "print"
+	outputPrint0 = "print0"
+	outputTree   = "tree"
+)
+
+// outputVersion is the version of the JSON and NDJSON output schema. It must
+// be bumped whenever existing fields change or are removed.
+const outputVersion = 1
+
+// rootPlan lists all clearable matches of a single root.
+type rootPlan struct {
+	root    string
+	matches []cleardir.Match
+}
+
+func (p rootPlan) paths() []string {
+	paths := make([]string, len(p.matches))
+	for i, m := range p.matches {
+		paths[i] = m.Path
+	}
+	return paths
+}
+
+// skippedDir describes an unreadable directory that was skipped.
+type skippedDir struct {
+	path string
+	err  error
+}
+
+// runSummary summarizes a single run.
+type runSummary struct {
+	plans      []rootPlan
+	skipped    []skippedDir
+	dry        bool
+	removed    int
+	failed     int
+	freedBytes int64
+	scanTime   time.Duration
+	removeTime time.Duration
+}
+
+func (s runSummary) matches() int {
+	n := 0
+	for _, p := range s.plans {
+		n += len(p.matches)
+	}
+	return n
+}
+
+// reporter reports the plan and results of a run to the user.
+type reporter interface {
+	// match reports a single clearable match as soon as it is found.
+	match(root string, m cleardir.Match)
+	// plan reports all matches once all roots have been scanned.
+	plan(plans []rootPlan)
+	// removal reports a single attempted removal.
+	removal(path string, err error)
+	// done reports the end of a run.
+	done(sum runSummary)
+}
+
+// embedsStats reports whether an output mode always includes statistics.
+func embedsStats(mode string) bool {
+	return mode == outputJSON || mode == outputNDJSON
+}
+
+func isOutputMode(mode string) bool {
+	switch mode {
+	case outputText, outputTree, outputJSON, outputNDJSON, outputPrint, outputPrint0:
+		return true
+	}
+	return false
+}
+
+// isHumanOutput reports whether an output mode is meant for humans, and may
+// thus be interleaved with prompts.
+func isHumanOutput(mode string) bool {
+	return mode == outputText || mode == outputTree
+}
+
+func newReporter(cmd *cobra.Command, opts *cleardirOpts, pf pathFormatter) reporter {
+	if opts.silent {
+		return nopReporter{}
+	}
+	switch opts.output {
+	case outputJSON:
+		return &jsonReporter{w: cmd.OutOrStdout()}
+	case outputNDJSON:
+		return &ndjsonReporter{enc: newJSONEncoder(cmd.OutOrStdout(), false)}
+	case outputPrint:
+		return pathsReporter{cmd.OutOrStdout(), pf, '\n'}
+	case outputPrint0:
+		return pathsReporter{cmd.OutOrStdout(), pf, 0}
+	case outputTree:
+		return treeReporter{textReporter{cmd, pf}, opts.treeDepth}
+	}
+	if opts.tui {
+		return tuiReporter{textReporter{cmd, pf}}
+	}
+	return textReporter{cmd, pf}
+}
+
+type nopReporter struct{}
+
+func (nopReporter) match(string, cleardir.Match) {}
+func (nopReporter) plan([]rootPlan)              {}
+func (nopReporter) removal(string, error)        {}
+func (nopReporter) done(runSummary)              {}
+
+type textReporter struct {
+	cmd *cobra.Command
+	pf  pathFormatter
+}
+
+func (r textReporter) match(root string, m cleardir.Match) {
+	r.cmd.Printf("- %s\n", r.pf.path(root, m))
+}
+
+func (r textReporter) plan(plans []rootPlan) {
+	st := runSummary{plans: plans}.stats()
+	if st.dirs+st.files == 0 {
+		r.cmd.Println("All clear!")
+		return
+	}
+	r.cmd.Printf("Can clear %s.\n", countsText(st.dirs, st.files))
+	if len(plans) > 1 {
+		for _, p := range plans {
+			r.cmd.Printf("  %d in %s\n", len(p.matches), r.pf.root(p.root))
+		}
+	}
+}
+
+func (textReporter) removal(string, error) {}
+
+// done prints the statistics block of any run that found clearable items.
+func (r textReporter) done(sum runSummary) {
+	st := sum.stats()
+	if st.dirs+st.files > 0 {
+		printStats(r.cmd.OutOrStdout(), st)
+	}
+}
+
+// pathsReporter prints undecorated match paths, each terminated by sep.
+//
+// Paths may be relative, but are never quoted or colored.
+type pathsReporter struct {
+	w   io.Writer
+	pf  pathFormatter
+	sep byte
+}
+
+func (r pathsReporter) match(root string, m cleardir.Match) {
+	_, _ = io.WriteString(r.w, r.pf.rel(root, m.Path)+string(r.sep))
+}
+
+func (pathsReporter) plan([]rootPlan)       {}
+func (pathsReporter) removal(string, error) {}
+func (pathsReporter) done(runSummary)       {}
+
+type jsonMatch struct {
+	Type    string `json:"type,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Root    string `json:"root"`
+	Path    string `json:"path"`
+	Kind    string `json:"kind"`
+	Size    int64  `json:"size"`
+	Rule    string `json:"rule"`
+	Depth   int    `json:"depth"`
+}
+
+type jsonRemoval struct {
+	Type    string  `json:"type,omitempty"`
+	Version int     `json:"version,omitempty"`
+	Path    string  `json:"path"`
+	OK      bool    `json:"ok"`
+	Error   *string `json:"error"`
+}
+
+type jsonRoot struct {
+	Root    string `json:"root"`
+	Matches int    `json:"matches"`
+}
+
+type jsonSkipped struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+type jsonStats struct {
+	Dirs          int            `json:"dirs"`
+	Files         int            `json:"files"`
+	Bytes         int64          `json:"bytes"`
+	Rules         map[string]int `json:"rules"`
+	InodesFreed   int            `json:"inodes_freed"`
+	BytesFreed    int64          `json:"bytes_freed"`
+	ScanSeconds   float64        `json:"scan_seconds"`
+	RemoveSeconds float64        `json:"remove_seconds"`
+}
+
+type jsonSummary struct {
+	Type    string        `json:"type,omitempty"`
+	Version int           `json:"version,omitempty"`
+	Dry     bool          `json:"dry"`
+	Roots   []jsonRoot    `json:"roots"`
+	Skipped []jsonSkipped `json:"skipped"`
+	Matches int           `json:"matches"`
+	Removed int           `json:"removed"`
+	Failed  int           `json:"failed"`
+	Stats   jsonStats     `json:"stats"`
+}
+
+type jsonDoc struct {
+	Version  int           `json:"version"`
+	Matches  []jsonMatch   `json:"matches"`
+	Removals []jsonRemoval `json:"removals"`
+	Summary  jsonSummary   `json:"summary"`
+}
+
+func newJSONMatch(root string, m cleardir.Match) jsonMatch {
+	return jsonMatch{
+		Root:  root,
+		Path:  m.Path,
+		Kind:  m.Kind.String(),
+		Size:  m.Size,
+		Rule:  m.Rule,
+		Depth: m.Depth,
+	}
+}
+
+func newJSONRemoval(path string, err error) jsonRemoval {
+	r := jsonRemoval{Path: path, OK: err == nil}
+	if err != nil {
+		msg := err.Error()
+		r.Error = &msg
+	}
+	return r
+}
+
+func newJSONSummary(sum runSummary) jsonSummary {
+	s := jsonSummary{
+		Dry:     sum.dry,
+		Roots:   make([]jsonRoot, len(sum.plans)),
+		Skipped: make([]jsonSkipped, len(sum.skipped)),
+		Matches: sum.matches(),
+		Removed: sum.removed,
+		Failed:  sum.failed,
+	}
+	for i, p := range sum.plans {
+		s.Roots[i] = jsonRoot{Root: p.root, Matches: len(p.matches)}
+	}
+	st := sum.stats()
+	s.Stats = jsonStats{
+		Dirs:          st.dirs,
+		Files:         st.files,
+		Bytes:         st.bytes,
+		Rules:         st.rules,
+		InodesFreed:   st.freed,
+		BytesFreed:    st.freedBytes,
+		ScanSeconds:   st.scanTime.Seconds(),
+		RemoveSeconds: st.removeTime.Seconds(),
+	}
+	for i, sk := range sum.skipped {
+		s.Skipped[i] = jsonSkipped{Path: sk.path, Error: sk.err.Error()}
+	}
+	return s
+}
+
+func newJSONEncoder(w io.Writer, indent bool) *json.Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	return enc
+}
+
+type jsonReporter struct {
+	w   io.Writer
+	doc jsonDoc
+}
+
+func (r *jsonReporter) match(root string, m cleardir.Match) {
+	r.doc.Matches = append(r.doc.Matches, newJSONMatch(root, m))
+}
+
+func (r *jsonReporter) plan([]rootPlan) {}
+
+func (r *jsonReporter) removal(path string, err error) {
+	r.doc.Removals = append(r.doc.Removals, newJSONRemoval(path, err))
+}
+
+func (r *jsonReporter) done(sum runSummary) {
+	r.doc.Version = outputVersion
+	if r.doc.Matches == nil {
+		r.doc.Matches = []jsonMatch{}
+	}
+	if r.doc.Removals == nil {
+		r.doc.Removals = []jsonRemoval{}
+	}
+	r.doc.Summary = newJSONSummary(sum)
+	_ = newJSONEncoder(r.w, true).Encode(r.doc)
+}
+
+type ndjsonReporter struct {
+	enc *json.Encoder
+}
+
+func (r *ndjsonReporter) match(root string, m cleardir.Match) {
+	jm := newJSONMatch(root, m)
+	jm.Type, jm.Version = "match", outputVersion
+	_ = r.enc.Encode(jm)
+}
+
+func (r *ndjsonReporter) plan([]rootPlan) {}
+
+func (r *ndjsonReporter) removal(path string, err error) {
+	jr := newJSONRemoval(path, err)
+	jr.Type, jr.Version = "removal", outputVersion
+	_ = r.enc.Encode(jr)
+}
+
+func (r *ndjsonReporter) done(sum runSummary) {
+	js := newJSONSummary(sum)
+	js.Type, js.Version = "summary", outputVersion
+	_ = r.enc.Encode(js)
+}
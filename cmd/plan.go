@@ -0,0 +1,508 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/echocrow/cleardir/internal/hooks"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	os "github.com/echocrow/osa"
+	"github.com/spf13/cobra"
+)
+
+// stdoutFlag denotes stdout in place of an output file path.
+const stdoutFlag = "-"
+
+// planVersion is the version of the plan file schema. It must be bumped
+// whenever existing fields change or are removed.
+const planVersion = 2
+
+// planFile is a saved plan of items to remove.
+type planFile struct {
+	Version int        `json:"version"`
+	Created time.Time  `json:"created"`
+	Roots   []string   `json:"roots"`
+	Items   []planItem `json:"items"`
+}
+
+// planItem is a single planned item along with its fingerprint.
+type planItem struct {
+	Root  string `json:"root"`
+	Path  string `json:"path"`
+	Kind  string `json:"kind"`
+	Inode uint64 `json:"inode"`
+	// Size is the size of the matched file in bytes, or 0 for directories.
+	Size int64 `json:"size"`
+	// StatSize is the size of the entry as reported by the file system, which
+	// is only compared to detect changes.
+	StatSize int64     `json:"stat_size"`
+	ModTime  time.Time `json:"mtime"`
+	Rule     string    `json:"rule,omitempty"`
+	Depth    int       `json:"depth"`
+}
+
+func (it planItem) match() cleardir.Match {
+	m := cleardir.Match{Path: it.Path, Size: it.Size, Rule: it.Rule, Depth: it.Depth}
+	if it.Kind == cleardir.KindDir.String() {
+		m.Kind = cleardir.KindDir
+	}
+	return m
+}
+
+func (it planItem) fingerprint() cleardir.Fingerprint {
+	return cleardir.Fingerprint{
+		Kind:    it.match().Kind,
+		Inode:   it.Inode,
+		Size:    it.StatSize,
+		ModTime: it.ModTime,
+	}
+}
+
+type planCmd struct {
+	cmd  *cobra.Command
+	opts planOpts
+}
+
+type planOpts struct {
+	cfg      string
+	fromFile string
+	trivials []string
+	maxDepth int
+	onError  string
+	jobs     int
+	out      string
+	git      gitOpts
+}
+
+func newPlanCmd() *planCmd {
+	pc := &planCmd{}
+	opts := &pc.opts
+
+	cmd := &cobra.Command{
+		Use:   "plan [PATH...]",
+		Short: "Save clearable items to a plan file for later review",
+		Long: heredoc.Doc(`
+			Plan scans like a dry run, and saves all clearable items to a plan file
+			along with their fingerprints, i.e. their kind, inode, size, and
+			modification time. The plan can then be reviewed, and executed via
+			"cleardir apply".
+		`),
+		Example: indentHeredoc(`
+		  cleardir plan --out plan.json /srv/data
+		  cleardir plan --out - | jq -r '.items[].path'
+		`),
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: completeDirs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPlan(cmd, opts, args)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.cfg, "config", "c", "", "specify the configuration file path")
+	cmd.Flags().StringVarP(&opts.fromFile, "from-file", "", "", flushHeredoc(`
+		read newline- or NUL-separated paths from a file;
+		use "-" to read from stdin
+	`))
+	cmd.Flags().StringSliceVarP(&opts.trivials, "files", "f", nil, "list files that can be deleted safely")
+	cmd.Flags().IntVarP(&opts.maxDepth, "max-depth", "d", -1, flushHeredoc(`
+		limit how many sub-directories to descend to at most;
+		use "-1" for no limit
+	`))
+	cmd.Flags().StringVarP(&opts.onError, "on-error", "", onErrorAbort, flushHeredoc(`
+		handle unreadable directories: "abort" the scan, or "skip" or "warn"
+		and treat them as non-empty
+	`))
+	cmd.Flags().IntVarP(&opts.jobs, "jobs", "j", 1, "read up to this many directories concurrently")
+	cmd.Flags().StringVarP(&opts.out, "out", "", "", "write the plan to this file; use \"-\" for stdout")
+	_ = cmd.MarkFlagRequired("out")
+	addGitFlags(cmd.Flags(), &opts.git)
+	_ = cmd.RegisterFlagCompletionFunc("on-error", completeValues(onErrorAbort, onErrorSkip, onErrorWarn))
+
+	pc.cmd = cmd
+	return pc
+}
+
+func runPlan(cmd *cobra.Command, opts *planOpts, args []string) error {
+	cfg, _, err := cleardir.ReadConfig(opts.cfg)
+	if err != nil {
+		return err
+	}
+	trivials := append(cfg.Clearables, opts.trivials...)
+	roots, err := resolveRoots(cmd, opts.fromFile, args)
+	if err != nil {
+		return err
+	}
+
+	skipped := []skippedDir{}
+	var onError func(string, error) error
+	switch opts.onError {
+	case onErrorAbort:
+	case onErrorSkip, onErrorWarn:
+		onError = func(path string, err error) error {
+			skipped = append(skipped, skippedDir{path, err})
+			return nil
+		}
+	default:
+		return fmt.Errorf("invalid on-error mode %q", opts.onError)
+	}
+	findOpts := cleardir.Options{
+		Trivials: trivials,
+		MaxDepth: opts.maxDepth,
+		OnError:  onError,
+		Jobs:     opts.jobs,
+		Sort:     true,
+	}
+	cmd.SilenceUsage = true
+	if err := opts.git.apply(&findOpts, roots); err != nil {
+		return err
+	}
+	finder := cleardir.NewFinder(findOpts)
+
+	plan := planFile{Version: planVersion, Created: time.Now().UTC(), Roots: roots, Items: []planItem{}}
+	plans := make([]rootPlan, len(roots))
+	for i, root := range roots {
+		plans[i] = rootPlan{root: root, matches: []cleardir.Match{}}
+		matches, errc := finder.Find(cmd.Context(), root)
+		for m := range matches {
+			plans[i].matches = append(plans[i].matches, m)
+		}
+		if err := <-errc; err != nil {
+			return err
+		}
+	}
+	if len(skipped) > 0 {
+		printSkipped(cmd, skipped, opts.onError == onErrorWarn)
+	}
+
+	// Matches carry no inodes or modification times, so fingerprints are read
+	// separately.
+	for _, p := range plans {
+		for _, m := range p.matches {
+			fp, err := cleardir.ReadFingerprint(m.Path)
+			if err != nil {
+				return err
+			}
+			plan.Items = append(plan.Items, planItem{
+				Root:     p.root,
+				Path:     m.Path,
+				Kind:     fp.Kind.String(),
+				Inode:    fp.Inode,
+				Size:     m.Size,
+				StatSize: fp.Size,
+				ModTime:  fp.ModTime,
+				Rule:     m.Rule,
+				Depth:    m.Depth,
+			})
+		}
+	}
+
+	if opts.out == stdoutFlag {
+		return newJSONEncoder(cmd.OutOrStdout(), true).Encode(plan)
+	}
+	var b bytes.Buffer
+	if err := newJSONEncoder(&b, true).Encode(plan); err != nil {
+		return err
+	}
+	if err := os.WriteFile(opts.out, b.Bytes(), 0o644); err != nil {
+		return err
+	}
+	rep := textReporter{cmd, pathFormatter{quote: quoteNone}}
+	for _, p := range plans {
+		for _, m := range p.matches {
+			rep.match(p.root, m)
+		}
+	}
+	rep.plan(plans)
+	cmd.Printf("Saved plan to %s.\n", opts.out)
+	return nil
+}
+
+type applyCmd struct {
+	cmd  *cobra.Command
+	opts applyOpts
+}
+
+type applyOpts struct {
+	cfg          string
+	dry          bool
+	yes          bool
+	jobs         int
+	output       string
+	lock         lockOpts
+	limits       limitOpts
+	snapshotPath string
+	auditPath    string
+}
+
+func newApplyCmd() *applyCmd {
+	ac := &applyCmd{}
+	opts := &ac.opts
+
+	cmd := &cobra.Command{
+		Use:   "apply PLAN",
+		Short: "Remove the items of a saved plan",
+		Long: heredoc.Doc(`
+			Apply removes exactly the items of a plan file saved via "cleardir plan".
+			Items whose fingerprint changed since the plan was saved are skipped and
+			reported, along with any planned directories containing them.
+		`),
+		Example: indentHeredoc(`
+		  cleardir apply plan.json
+		  cleardir apply --dry plan.json
+		`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runApply(cmd, opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.cfg, "config", "c", "", "specify the configuration file path")
+	cmd.Flags().BoolVarP(&opts.dry, "dry", "", false, "only check and list the items of the plan")
+	cmd.Flags().BoolVarP(&opts.yes, "yes", "y", false, "skip and confirm prompts")
+	cmd.Flags().IntVarP(&opts.jobs, "jobs", "j", 1, "remove up to this many entries concurrently")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", outputText, flushHeredoc(`
+		print results as "text", a "json" document, or "ndjson"
+		lines; JSON output requires "--yes" or "--dry"
+	`))
+	addLockFlags(cmd.Flags(), &opts.lock)
+	addLimitFlags(cmd.Flags(), &opts.limits)
+	addSnapshotFlag(cmd.Flags(), &opts.snapshotPath)
+	addAuditFlag(cmd.Flags(), &opts.auditPath)
+	_ = cmd.RegisterFlagCompletionFunc("output", completeValues(outputText, outputJSON, outputNDJSON))
+
+	ac.cmd = cmd
+	return ac
+}
+
+// changedItem is a planned item that is skipped, as it or its contents
+// changed since the plan was saved.
+type changedItem struct {
+	path   string
+	reason string
+}
+
+func runApply(cmd *cobra.Command, opts *applyOpts, planPath string) (err error) {
+	plan, err := readPlan(planPath)
+	if err != nil {
+		return err
+	}
+	cfg, _, err := cleardir.ReadConfig(opts.cfg)
+	if err != nil {
+		return err
+	}
+	limits, err := opts.limits.resolve(cmd.Flags(), cfg)
+	if err != nil {
+		return err
+	}
+	hks, err := hooks.Parse(cfg)
+	if err != nil {
+		return err
+	}
+	hks.Output = cmd.ErrOrStderr()
+	auditPath, err := resolveAuditPath(opts.auditPath, cfg)
+	if err != nil {
+		return err
+	}
+	pf := pathFormatter{quote: quoteNone}
+	var rep reporter
+	switch opts.output {
+	case outputText:
+		rep = textReporter{cmd, pf}
+	case outputJSON:
+		rep = &jsonReporter{w: cmd.OutOrStdout()}
+	case outputNDJSON:
+		rep = &ndjsonReporter{enc: newJSONEncoder(cmd.OutOrStdout(), false)}
+	default:
+		return fmt.Errorf("invalid output mode %q", opts.output)
+	}
+	rawOut := opts.output != outputText
+	if rawOut && !opts.yes && !opts.dry {
+		return fmt.Errorf("%s output requires \"--yes\" or \"--dry\"", opts.output)
+	}
+	if opts.snapshotPath != "" && opts.dry {
+		return errors.New("\"--snapshot-report\" cannot be combined with \"--dry\"")
+	}
+	locker, err := opts.lock.locker()
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	sum := runSummary{dry: opts.dry}
+	var aud *auditLog
+	// Like runs that scan, only applying plans for real needs locks, hooks, and
+	// audits.
+	if !opts.dry {
+		var lk *cleardir.Lock
+		if lk, err = lockRoots(cmd.Context(), locker, plan.Roots); err != nil {
+			return err
+		}
+		defer lk.Unlock()
+
+		defer func() {
+			if err == nil || err == errAborted {
+				return
+			}
+			env := hooks.Env{Roots: plan.Roots, Count: sum.removed, Bytes: sum.freedBytes, Err: err}
+			// Failed runs may have been interrupted, but still deserve a hook.
+			if herr := hks.Run(context.Background(), hooks.OnError, env, nil); herr != nil {
+				cmd.PrintErrf("Warning: %s.\n", herr)
+			}
+		}()
+
+		if aud, err = openAuditLog(auditPath, "", plan.Roots); err != nil {
+			return err
+		}
+		defer func() {
+			if aerr := aud.finish(sum, err); aerr != nil && err == nil {
+				err = aerr
+			}
+		}()
+	}
+
+	plans, changed := checkPlan(plan)
+	sum.plans = plans
+	if len(changed) > 0 {
+		cmd.PrintErrf("Warning: Skipping %d changed items:\n", len(changed))
+		for _, c := range changed {
+			cmd.PrintErrf("  %s (%s)\n", c.path, c.reason)
+		}
+	}
+	dels := []string{}
+	sizes := map[string]int64{}
+	for _, p := range plans {
+		for _, m := range p.matches {
+			rep.match(p.root, m)
+			sizes[m.Path] = m.Size
+		}
+		dels = append(dels, p.paths()...)
+	}
+	rep.plan(plans)
+	if len(dels) == 0 || opts.dry {
+		rep.done(sum)
+		return nil
+	}
+	if err := opts.limits.check(limits, dels, sizes); err != nil {
+		return err
+	}
+	if !rawOut && !confirm(cmd, "Continue?", 1, opts.yes) {
+		return errAborted
+	}
+
+	if hks.Has(hooks.PreRemove) {
+		if err := runPreRemoveHook(cmd.Context(), hks, hooks.Env{Roots: plan.Roots}, plans, dels); err != nil {
+			return err
+		}
+	}
+
+	aud.plan(plans)
+	// Post-remove hooks receive the same objects as NDJSON output.
+	var results bytes.Buffer
+	resultsRep := &ndjsonReporter{newJSONEncoder(&results, false)}
+	remover := cleardir.Remover{
+		Jobs: opts.jobs,
+		OnRemove: func(path string, err error) {
+			if err != nil {
+				sum.failed++
+			} else {
+				sum.removed++
+				sum.freedBytes += sizes[path]
+			}
+			rep.removal(path, err)
+			resultsRep.removal(path, err)
+			aud.removal(path, err)
+		},
+	}
+	var before snapshot
+	if opts.snapshotPath != "" {
+		if before, err = takeSnapshot(plan.Roots); err != nil {
+			return err
+		}
+	}
+	err = remover.Remove(cmd.Context(), dels...)
+	rep.done(sum)
+
+	if before != nil {
+		if serr := reportSnapshot(opts.snapshotPath, before, plan.Roots, dels); serr != nil && err == nil {
+			err = serr
+		}
+	}
+
+	resultsRep.done(sum)
+	env := hooks.Env{Roots: plan.Roots, Count: sum.removed, Bytes: sum.freedBytes}
+	if herr := hks.Run(cmd.Context(), hooks.PostRemove, env, results.Bytes()); herr != nil {
+		cmd.PrintErrf("Warning: %s.\n", herr)
+	}
+	return err
+}
+
+func readPlan(path string) (planFile, error) {
+	plan := planFile{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return plan, err
+	}
+	if err := json.Unmarshal(b, &plan); err != nil {
+		return plan, fmt.Errorf("%s: invalid plan: %w", path, err)
+	}
+	if plan.Version != planVersion {
+		return plan, fmt.Errorf("%s: unsupported plan version %d", path, plan.Version)
+	}
+	return plan, nil
+}
+
+// checkPlan compares the planned items to their current fingerprints, and
+// returns the unchanged items by root, and the changed items.
+//
+// Planned directories containing changed items are skipped as well, as they
+// could not be removed anyway.
+func checkPlan(plan planFile) ([]rootPlan, []changedItem) {
+	planned := make(map[string]bool, len(plan.Items))
+	for _, it := range plan.Items {
+		planned[filepath.Clean(it.Path)] = true
+	}
+
+	reasons := map[string]string{}
+	for _, it := range plan.Items {
+		reason := ""
+		fp, err := cleardir.ReadFingerprint(it.Path)
+		switch {
+		case os.IsNotExist(err):
+			reason = "missing"
+		case err != nil:
+			reason = err.Error()
+		default:
+			reason = fp.Diff(it.fingerprint())
+		}
+		if reason == "" {
+			continue
+		}
+		reasons[filepath.Clean(it.Path)] = reason
+		for dir := filepath.Dir(filepath.Clean(it.Path)); planned[dir]; dir = filepath.Dir(dir) {
+			if _, ok := reasons[dir]; !ok {
+				reasons[dir] = "contents changed"
+			}
+		}
+	}
+
+	plans := []rootPlan{}
+	changed := []changedItem{}
+	for _, it := range plan.Items {
+		if reason, ok := reasons[filepath.Clean(it.Path)]; ok {
+			changed = append(changed, changedItem{it.Path, reason})
+			continue
+		}
+		if len(plans) == 0 || plans[len(plans)-1].root != it.Root {
+			plans = append(plans, rootPlan{root: it.Root, matches: []cleardir.Match{}})
+		}
+		p := &plans[len(plans)-1]
+		p.matches = append(p.matches, it.match())
+	}
+	return plans, changed
+}
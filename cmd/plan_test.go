@@ -0,0 +1,158 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/echocrow/fsnap/dirsnap"
+	"github.com/echocrow/osa/testos"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdPlanApply(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	err := fsd{
+		"a":    fsd{"x": nil},
+		"b":    fsd{"x": nil, "e": fsd{}},
+		"c":    fsd{},
+		"keep": nil,
+	}.Write(dir)
+	require.NoError(t, err)
+	testos.RequireWrite(t, v, path.Join(dir, "a", "x"), "xyz")
+	cfgPath := path.Join(vos.MkTempDir(v), "cfg")
+	testos.RequireWrite(t, v, cfgPath, "x\n")
+	planPath := path.Join(vos.MkTempDir(v), "plan.json")
+
+	var out bytes.Buffer
+	run := func(args ...string) error {
+		out.Reset()
+		c := newCmd()
+		c.SetOut(&out)
+		c.SetErr(&out)
+		c.SetArgs(args)
+		return c.Execute()
+	}
+
+	require.NoError(t, run("plan", "-c", cfgPath, "--out", planPath, dir))
+	assertGolden(t, "plan.txt", normalizeOutput(strings.ReplaceAll(out.String(), planPath, "plan.json"), dir))
+
+	b, err := v.ReadFile(planPath)
+	require.NoError(t, err)
+	var plan struct {
+		Version int
+		Roots   []string
+		Items   []struct {
+			Path     string
+			Kind     string
+			Size     int64
+			StatSize int64 `json:"stat_size"`
+		}
+	}
+	require.NoError(t, json.Unmarshal(b, &plan))
+	assert.Equal(t, 2, plan.Version)
+	assert.Equal(t, []string{dir}, plan.Roots)
+	require.Len(t, plan.Items, 6)
+	assert.Equal(t, filepath.Join(dir, "a", "x"), plan.Items[0].Path)
+	assert.Equal(t, "file", plan.Items[0].Kind)
+	assert.Equal(t, "dir", plan.Items[1].Kind)
+	assert.Equal(t, int64(3), plan.Items[0].Size)
+	assert.Equal(t, int64(3), plan.Items[0].StatSize)
+	// Directories free no bytes, whatever their size on disk.
+	assert.Zero(t, plan.Items[1].Size)
+
+	// Changed items are skipped along with their planned parents.
+	require.NoError(t, v.WriteFile(filepath.Join(dir, "b", "x"), []byte("changed"), 0o644))
+	require.NoError(t, v.Remove(filepath.Join(dir, "c")))
+
+	require.NoError(t, run("apply", "--dry", planPath))
+	assertGolden(t, "apply-dry.txt", normalizeOutput(out.String(), dir))
+	require.NoError(t, run("apply", "-y", planPath))
+	assertGolden(t, "apply.txt", normalizeOutput(out.String(), dir))
+
+	gotFsd, err := dirsnap.Read(dir, -1)
+	require.NoError(t, err)
+	assert.Equal(t, fsd{"b": fsd{"x": nil}, "keep": nil}, gotFsd)
+
+	require.NoError(t, v.WriteFile(planPath, []byte(`{"version":1}`), 0o644))
+	err = run("apply", "-y", planPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported plan version 1")
+
+	err = run("plan", "-c", cfgPath, dir)
+	assert.Error(t, err)
+}
+
+func TestCmdApplySafeguards(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	err := fsd{"a": fsd{"x": nil}, "b": fsd{}, "keep": nil}.Write(dir)
+	require.NoError(t, err)
+	cfgPath := path.Join(vos.MkTempDir(v), "cfg")
+	planPath := path.Join(vos.MkTempDir(v), "plan.json")
+	reportPath := path.Join(vos.MkTempDir(v), "report.txt")
+
+	var stdout, stderr bytes.Buffer
+	run := func(cfg string, args ...string) error {
+		require.NoError(t, v.WriteFile(cfgPath, []byte(cleardir.ConfigHeader+"\nx\n"+cfg), 0o644))
+		stdout.Reset()
+		stderr.Reset()
+		c := newCmd()
+		c.SetOut(&stdout)
+		c.SetErr(&stderr)
+		c.SetArgs(append(args, "-c", cfgPath))
+		return c.Execute()
+	}
+	require.NoError(t, run("", "plan", "--out", planPath, dir))
+	srcFsd, err := dirsnap.Read(dir, -1)
+	require.NoError(t, err)
+
+	err = run("[limits]\nmax-delete = 2\n", "apply", "-y", planPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the limit of 2")
+
+	err = run("[hooks]\npre-remove = exit 1\non-error = echo \"on-error $CLEARDIR_ERROR\"\n", "apply", "-y", planPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "removal vetoed")
+	assert.Contains(t, stderr.String(), "on-error removal vetoed")
+
+	gotFsd, err := dirsnap.Read(dir, -1)
+	require.NoError(t, err)
+	assert.Equal(t, srcFsd, gotFsd)
+
+	err = run("", "apply", "-o", "ndjson", planPath)
+	assert.Error(t, err)
+
+	err = run("", "apply", "--dry", "--snapshot-report", reportPath, planPath)
+	assert.Error(t, err)
+
+	err = run("[hooks]\npost-remove = echo \"post-remove $CLEARDIR_COUNT\"\n", "apply", "-y", "-o", "ndjson", "--max-delete", "2", "--force-large", "--snapshot-report", reportPath, planPath)
+	require.NoError(t, err)
+	report, err := v.ReadFile(reportPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(report), "# OK: Nothing changed outside of the plan.")
+	assert.Contains(t, stderr.String(), "post-remove 3\n")
+	types := []string{}
+	dec := json.NewDecoder(&stdout)
+	for dec.More() {
+		var obj struct{ Type string }
+		require.NoError(t, dec.Decode(&obj))
+		types = append(types, obj.Type)
+	}
+	assert.Equal(t, []string{"match", "match", "match", "removal", "removal", "removal", "summary"}, types)
+
+	gotFsd, err = dirsnap.Read(dir, -1)
+	require.NoError(t, err)
+	assert.Equal(t, fsd{"keep": nil}, gotFsd)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+)
+
+const (
+	progressInterval = 100 * time.Millisecond
+	progressWidth    = 80
+	progressBarWidth = 30
+)
+
+// progressLine draws a single, continuously updated status line.
+//
+// A nil progressLine is valid and draws nothing.
+type progressLine struct {
+	w     io.Writer
+	mu    sync.Mutex
+	start time.Time
+	last  time.Time
+	drawn bool
+}
+
+func newProgressLine(w io.Writer) *progressLine {
+	return &progressLine{w: w, start: time.Now()}
+}
+
+// scan draws the progress of a scan, unless throttled.
+func (l *progressLine) scan(p cleardir.Progress) {
+	if l == nil || !l.due() {
+		return
+	}
+	rate := float64(p.Dirs) / time.Since(l.start).Seconds()
+	status := fmt.Sprintf("Scanned %d dirs, found %d (%.0f dirs/s) ", p.Dirs, p.Matches, rate)
+	l.draw(status + truncatePath(p.Path, progressWidth-len(status)))
+}
+
+// remove draws the progress of a removal as a bar, unless throttled.
+func (l *progressLine) remove(done, total int) {
+	if l == nil || (done < total && !l.due()) {
+		return
+	}
+	filled := progressBarWidth * done / total
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	l.draw(fmt.Sprintf("Removing [%s] %d/%d (%d%%)", bar, done, total, 100*done/total))
+}
+
+// clear erases the line, if drawn.
+func (l *progressLine) clear() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.drawn {
+		fmt.Fprint(l.w, "\r\x1b[K")
+		l.drawn = false
+	}
+}
+
+// due reports whether enough time has passed since the last draw.
+func (l *progressLine) due() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.last) < progressInterval {
+		return false
+	}
+	l.last = now
+	return true
+}
+
+func (l *progressLine) draw(s string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprint(l.w, "\r\x1b[K"+s)
+	l.drawn = true
+}
+
+// truncatePath shortens path to at most width bytes by eliding its start.
+// Multi-byte characters are never split.
+func truncatePath(path string, width int) string {
+	const ellipsis = "..."
+	if len(path) <= width {
+		return path
+	}
+	if width <= len(ellipsis) {
+		return ""
+	}
+	i := len(path) - width + len(ellipsis)
+	for i < len(path) && !utf8.RuneStart(path[i]) {
+		i++
+	}
+	return ellipsis + path[i:]
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	os "github.com/echocrow/osa"
+	"github.com/spf13/cobra"
+)
+
+const stdinFlag = "-"
+
+// resolveRoots collects, resolves, and deduplicates all roots to scan.
+func resolveRoots(cmd *cobra.Command, fromFile string, args []string) ([]string, error) {
+	rawRoots := append([]string{}, args...)
+	if fromFile != "" {
+		fileRoots, err := readRootsFile(cmd, fromFile)
+		if err != nil {
+			return nil, err
+		}
+		rawRoots = append(rawRoots, fileRoots...)
+	}
+	if len(rawRoots) == 0 {
+		rawRoots = []string{""}
+	}
+
+	roots := make([]string, len(rawRoots))
+	for i, r := range rawRoots {
+		root, err := filepath.Abs(r)
+		if err != nil {
+			return nil, err
+		}
+		roots[i] = root
+	}
+	return cleardir.DedupeRoots(roots), nil
+}
+
+func readRootsFile(cmd *cobra.Command, path string) ([]string, error) {
+	var b []byte
+	var err error
+	if path == stdinFlag {
+		b, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return splitRoots(b), nil
+}
+
+// splitRoots splits NUL-separated or, lacking any NUL, newline-separated
+// roots.
+func splitRoots(b []byte) []string {
+	sep := []byte{'\n'}
+	if bytes.IndexByte(b, 0) >= 0 {
+		sep = []byte{0}
+	}
+	roots := []string{}
+	for _, r := range bytes.Split(b, sep) {
+		if sep[0] == '\n' {
+			r = bytes.TrimSuffix(r, []byte{'\r'})
+		}
+		if len(r) > 0 {
+			roots = append(roots, string(r))
+		}
+	}
+	return roots
+}
@@ -0,0 +1,117 @@
+package cmd_test
+
+import (
+	"fmt"
+	"path"
+	"testing"
+
+	"github.com/echocrow/fsnap/dirsnap"
+	"github.com/echocrow/osa/testos"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdMultipleRoots(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{
+		"a": fsd{"d": fsd{}, "f": nil},
+		"b": fsd{"d": fsd{}, "f": nil},
+		"c": fsd{"d": fsd{}, "f": nil},
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		file     string // Formatted with the test dir.
+		sendIn   string // Formatted with the test dir.
+		wantLeft []string
+		wantOut  string
+	}{
+		{
+			"Args",
+			[]string{"a", "b"}, "", "",
+			[]string{"c"},
+			`(?s)Can clear 2 dirs\.\n  1 in .+/a\n  1 in .+/b\n`,
+		},
+		{
+			"Nested Args",
+			[]string{"a/d", "a", "b", "a"}, "", "",
+			[]string{"c"},
+			`(?s)Can clear 2 dirs\.\n  1 in .+/a\n  1 in .+/b\n`,
+		},
+		{
+			"File",
+			[]string{"a"}, "%[1]s/b\n%[1]s/c\n\n", "",
+			nil,
+			`Can clear 3 dirs\.`,
+		},
+		{
+			"Stdin Lines",
+			[]string{"--from-file", "-"}, "", "%[1]s/a\r\n%[1]s/c\n",
+			[]string{"b"},
+			`Can clear 2 dirs\.`,
+		},
+		{
+			"Stdin NUL",
+			[]string{"--from-file", "-"}, "", "%[1]s/b\x00%[1]s/c\x00",
+			[]string{"a"},
+			`Can clear 2 dirs\.`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+
+			stdin, stdout, stderr := vos.GetStdio(v)
+			if tc.sendIn != "" {
+				stdin.Write([]byte(fmt.Sprintf(tc.sendIn, dir)))
+			}
+
+			args := []string{"-y"}
+			if tc.file != "" {
+				rootsPath := path.Join(vos.MkTempDir(v), "roots")
+				testos.RequireWrite(t, v, rootsPath, fmt.Sprintf(tc.file, dir))
+				args = append(args, "--from-file", rootsPath)
+			}
+			for _, a := range tc.args {
+				if a != "-" && a != "--from-file" {
+					a = path.Join(dir, a)
+				}
+				args = append(args, a)
+			}
+
+			err = execWithArgs(args...)
+			require.NoError(t, err)
+			assert.Regexp(t, tc.wantOut, stdout)
+			assert.Empty(t, stderr)
+
+			wantFsd := fsd{}
+			for n, d := range srcFsd {
+				wantFsd[n] = fsd{"f": nil}
+				for _, l := range tc.wantLeft {
+					if l == n {
+						wantFsd[n] = d
+					}
+				}
+			}
+			gotFsd, fsdErr := dirsnap.Read(dir, -1)
+			require.NoError(t, fsdErr)
+			assert.Equal(t, wantFsd, gotFsd)
+		})
+
+		vos.ClearStdio(v)
+	}
+}
+
+func TestCmdStdinRootsRequiresNoPrompt(t *testing.T) {
+	_, reset := vos.Patch()
+	defer reset()
+
+	err := execWithArgs("--from-file", "-")
+	assert.Error(t, err)
+}
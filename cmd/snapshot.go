@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/echocrow/fsnap/dirsnap"
+	os "github.com/echocrow/osa"
+	"github.com/spf13/pflag"
+)
+
+func addSnapshotFlag(fs *pflag.FlagSet, path *string) {
+	fs.StringVarP(path, "snapshot-report", "", "", flushHeredoc(`
+		snapshot all roots before and after removing, write the
+		difference to this file, and fail if anything outside
+		of the plan changed; files are compared by size and
+		modification time, and directories by presence only
+	`))
+}
+
+// snapshot holds snapshots of the contents of multiple roots, flattened into
+// slash-separated paths relative to their root.
+type snapshot map[string]map[string]snapshotEntry
+
+// snapshotEntry describes a snapshotted file or directory.
+//
+// Directories only record their kind, as removing their contents changes their
+// size and modification time.
+type snapshotEntry struct {
+	isDir   bool
+	size    int64
+	modTime time.Time
+}
+
+func takeSnapshot(roots []string) (snapshot, error) {
+	s := snapshot{}
+	for _, root := range roots {
+		d, err := dirsnap.Read(root, -1)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot of %s: %w", root, err)
+		}
+		entries, err := snapshotEntries(root, d)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot of %s: %w", root, err)
+		}
+		s[root] = entries
+	}
+	return s, nil
+}
+
+// snapshotEntries flattens d of root into slash-separated paths relative to
+// root, and reads the size and modification time of all files.
+func snapshotEntries(root string, d dirsnap.Dirs) (map[string]snapshotEntry, error) {
+	entries := map[string]snapshotEntry{}
+	var walk func(prefix string, d dirsnap.Dirs) error
+	walk = func(prefix string, d dirsnap.Dirs) error {
+		for name, sub := range d {
+			p := path.Join(prefix, name)
+			if sub != nil {
+				entries[p] = snapshotEntry{isDir: true}
+				if err := walk(p, sub); err != nil {
+					return err
+				}
+				continue
+			}
+			info, err := os.Stat(filepath.Join(root, filepath.FromSlash(p)))
+			if err != nil {
+				return err
+			}
+			entries[p] = snapshotEntry{size: info.Size(), modTime: info.ModTime()}
+		}
+		return nil
+	}
+	return entries, walk("", d)
+}
+
+// changed reports whether e differs from an earlier entry.
+func (e snapshotEntry) changed(earlier snapshotEntry) bool {
+	return e.isDir != earlier.isDir || e.size != earlier.size || !e.modTime.Equal(earlier.modTime)
+}
+
+// rootDiff describes how the contents of a root changed during removal.
+type rootDiff struct {
+	root          string
+	before, after int
+	// removed lists planned entries that disappeared.
+	removed []string
+	// remaining lists planned entries that are still present.
+	remaining []string
+	// unexpected lists changes outside of the plan, prefixed "-" for removed,
+	// "+" for added, and "~" for changed entries.
+	unexpected []string
+}
+
+// diffSnapshots compares the snapshots before and after removing planned
+// paths.
+func diffSnapshots(before, after snapshot, roots, planned []string) []rootDiff {
+	isPlanned := make(map[string]bool, len(planned))
+	for _, p := range planned {
+		isPlanned[filepath.Clean(p)] = true
+	}
+	diffs := make([]rootDiff, 0, len(roots))
+	for _, root := range roots {
+		b, a := before[root], after[root]
+		d := rootDiff{root: root, before: len(b), after: len(a)}
+		for rel, e := range b {
+			name := rel
+			if e.isDir {
+				name += "/"
+			}
+			planned := isPlanned[filepath.Join(root, filepath.FromSlash(rel))]
+			ae, ok := a[rel]
+			switch {
+			case !ok && planned:
+				d.removed = append(d.removed, name)
+			case !ok:
+				d.unexpected = append(d.unexpected, "- "+name)
+			case ae.changed(e):
+				d.unexpected = append(d.unexpected, "~ "+name)
+			case planned:
+				d.remaining = append(d.remaining, name)
+			}
+		}
+		for rel, e := range a {
+			if _, ok := b[rel]; !ok {
+				name := rel
+				if e.isDir {
+					name += "/"
+				}
+				d.unexpected = append(d.unexpected, "+ "+name)
+			}
+		}
+		sort.Strings(d.removed)
+		sort.Strings(d.remaining)
+		sort.Slice(d.unexpected, func(i, j int) bool {
+			return d.unexpected[i][2:] < d.unexpected[j][2:]
+		})
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// writeSnapshotReport writes a report of diffs to path, and returns the number
+// of unexpected changes.
+func writeSnapshotReport(path string, created time.Time, diffs []rootDiff) (int, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# cleardir snapshot report, created %s\n", created.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "# Files are compared by size and modification time, and directories by presence only.\n")
+	removed, unexpected := 0, 0
+	for _, d := range diffs {
+		fmt.Fprintf(&b, "\n# Root: %s\n", d.root)
+		fmt.Fprintf(&b, "# Entries: %d before, %d after\n", d.before, d.after)
+		for _, p := range d.removed {
+			fmt.Fprintf(&b, "- %s\n", p)
+		}
+		if len(d.remaining) > 0 {
+			fmt.Fprintf(&b, "# Planned but not removed:\n")
+			for _, p := range d.remaining {
+				fmt.Fprintf(&b, "= %s\n", p)
+			}
+		}
+		if len(d.unexpected) > 0 {
+			fmt.Fprintf(&b, "# Changed outside of the plan:\n")
+			for _, p := range d.unexpected {
+				fmt.Fprintf(&b, "! %s\n", p)
+			}
+		}
+		removed += len(d.removed)
+		unexpected += len(d.unexpected)
+	}
+	fmt.Fprintf(&b, "\n# Removed %s as planned.\n", plural(removed, "item"))
+	if unexpected == 0 {
+		fmt.Fprintf(&b, "# OK: Nothing changed outside of the plan.\n")
+	} else {
+		fmt.Fprintf(&b, "# FAILED: %s changed outside of the plan.\n", plural(unexpected, "item"))
+	}
+	return unexpected, os.WriteFile(path, b.Bytes(), 0o644)
+}
+
+// reportSnapshot takes a snapshot after removing planned paths, and writes a
+// report of its differences to the snapshot before to path. It fails if
+// anything outside of the plan changed.
+func reportSnapshot(path string, before snapshot, roots, planned []string) error {
+	after, err := takeSnapshot(roots)
+	if err != nil {
+		return err
+	}
+	diffs := diffSnapshots(before, after, roots, planned)
+	n, err := writeSnapshotReport(path, time.Now(), diffs)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("snapshot mismatch: %s changed outside of the plan; see %s", plural(n, "item"), path)
+	}
+	return nil
+}
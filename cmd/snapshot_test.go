@@ -0,0 +1,113 @@
+package cmd_test
+
+import (
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/echocrow/osa"
+	"github.com/echocrow/osa/testos"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var createdRe = regexp.MustCompile(`(created )\S+`)
+
+func TestCmdSnapshotReport(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{
+		"a":    fsd{"x": nil, "e": fsd{}},
+		"b":    fsd{"keep": nil},
+		"keep": nil,
+	}
+	tests := []struct {
+		name    string
+		sneak   func(dir string)
+		want    string
+		wantErr string
+	}{
+		{"OK", nil, "snapshot-ok.txt", ""},
+		{
+			"Mismatch",
+			func(dir string) {
+				_ = v.Remove(path.Join(dir, "b", "keep"))
+				_ = v.WriteFile(path.Join(dir, "b", "new"), nil, 0o644)
+			},
+			"snapshot-mismatch.txt",
+			"snapshot mismatch: 2 items changed outside of the plan",
+		},
+		{
+			"Modified",
+			func(dir string) {
+				_ = v.WriteFile(path.Join(dir, "keep"), []byte("changed"), 0o644)
+			},
+			"snapshot-modified.txt",
+			"snapshot mismatch: 1 item changed outside of the plan",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+			reportPath := path.Join(vos.MkTempDir(v), "report.txt")
+
+			if tc.sneak != nil {
+				// Another process changes the tree while removing.
+				defer osa.Patch(sneakyOS{v, path.Join(dir, "a", "x"), func() {
+					tc.sneak(dir)
+				}})()
+			}
+
+			err = execWithArgsInDir(dir, "-y", "-f", "x", "--snapshot-report", reportPath)
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			got, err := v.ReadFile(reportPath)
+			require.NoError(t, err)
+			assertGolden(t, tc.want, normalizeOutput(createdRe.ReplaceAllString(string(got), "${1}$$TIME"), dir))
+		})
+
+		vos.ClearStdio(v)
+	}
+
+	err := execWithArgs("--dry", "--snapshot-report", "report.txt")
+	assert.Error(t, err)
+
+	t.Run("Job", func(t *testing.T) {
+		dir := vos.MkTempDir(v)
+		require.NoError(t, srcFsd.Write(dir))
+		tmp := vos.MkTempDir(v)
+		reportPath := path.Join(tmp, "report.txt")
+		cfgPath := path.Join(tmp, "cfg")
+		testos.RequireWrite(t, v, cfgPath, cleardir.ConfigHeader+"\nx\n[job all]\nroots = "+dir+"\nsnapshot-report = "+reportPath)
+
+		require.NoError(t, execWithArgs("jobs", "run", "all", "-c", cfgPath))
+		got, err := v.ReadFile(reportPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(got), "# Removed 3 items as planned.\n# OK: Nothing changed outside of the plan.")
+	})
+}
+
+// sneakyOS wraps an OS abstraction and calls sneak once path is removed.
+type sneakyOS struct {
+	osa.I
+	path  string
+	sneak func()
+}
+
+func (o sneakyOS) Remove(name string) error {
+	err := o.I.Remove(name)
+	if name == o.path {
+		o.sneak()
+	}
+	return err
+}
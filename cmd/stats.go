@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/echocrow/cleardir/internal/humanize"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+)
+
+// runStats aggregates statistics of a run.
+type runStats struct {
+	dirs       int
+	files      int
+	bytes      int64
+	rules      map[string]int
+	freed      int
+	freedBytes int64
+	scanTime   time.Duration
+	removeTime time.Duration
+	// dry denotes statistics of a run that removed nothing by design.
+	dry bool
+}
+
+// stats aggregates statistics of all plans and removals.
+func (s runSummary) stats() runStats {
+	st := runStats{
+		rules:      map[string]int{},
+		freed:      s.removed,
+		freedBytes: s.freedBytes,
+		scanTime:   s.scanTime,
+		removeTime: s.removeTime,
+		dry:        s.dry,
+	}
+	for _, p := range s.plans {
+		for _, m := range p.matches {
+			if m.Kind == cleardir.KindDir {
+				st.dirs++
+				continue
+			}
+			st.files++
+			st.bytes += m.Size
+			st.rules[m.Rule]++
+		}
+	}
+	return st
+}
+
+// countsText describes the given numbers of directories and files.
+func countsText(dirs, files int) string {
+	switch {
+	case dirs > 0 && files > 0:
+		return plural(dirs, "dir") + " and " + plural(files, "file")
+	case dirs > 0:
+		return plural(dirs, "dir")
+	}
+	return plural(files, "file")
+}
+
+// printStats prints a human-readable statistics block. Statistics of dry runs
+// omit all removal lines.
+func printStats(w io.Writer, st runStats) {
+	fmt.Fprintln(w, "Stats:")
+	fmt.Fprintf(w, "  Directories:  %d\n", st.dirs)
+	fmt.Fprintf(w, "  Files:        %d\n", st.files)
+	fmt.Fprintf(w, "  Size:         %s\n", humanize.Bytes(st.bytes))
+	if !st.dry {
+		fmt.Fprintf(w, "  Inodes freed: %d\n", st.freed)
+		fmt.Fprintf(w, "  Bytes freed:  %s\n", humanize.Bytes(st.freedBytes))
+	}
+	if len(st.rules) > 0 {
+		fmt.Fprintln(w, "  By rule:")
+		rules := sortedRules(st.rules)
+		width := 0
+		for _, r := range rules {
+			if len(r) > width {
+				width = len(r)
+			}
+		}
+		for _, r := range rules {
+			fmt.Fprintf(w, "    %-*s %d\n", width+1, r+":", st.rules[r])
+		}
+	}
+	fmt.Fprintf(w, "  Scan time:    %s\n", formatDuration(st.scanTime))
+	if !st.dry {
+		fmt.Fprintf(w, "  Removal time: %s\n", formatDuration(st.removeTime))
+	}
+}
+
+// sortedRules sorts rules by descending count, then by name.
+func sortedRules(rules map[string]int) []string {
+	sorted := make([]string, 0, len(rules))
+	for r := range rules {
+		sorted = append(sorted, r)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if rules[a] != rules[b] {
+			return rules[a] > rules[b]
+		}
+		return a < b
+	})
+	return sorted
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
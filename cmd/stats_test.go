@@ -0,0 +1,70 @@
+package cmd_test
+
+import (
+	"io"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/echocrow/osa/testos"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdStats(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{
+		"a": fsd{"x": nil, "y": nil},
+		"b": fsd{"x": nil, "d": fsd{}},
+		"c": fsd{"keep": nil, "x": nil},
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStdout string
+		wantStderr string
+	}{
+		{"Dry", []string{"--dry"}, "stats-dry.txt", ""},
+		{"Run", []string{"-y"}, "stats-run.txt", ""},
+		{"Run Forced", []string{"--stats", "-y"}, "stats-run.txt", ""},
+		{"Silent", []string{"-s"}, "", ""},
+		{"Silent Forced", []string{"--stats", "-s"}, "", "stats-silent.txt"},
+		{"Print0", []string{"-0", "-y"}, "", ""},
+		{"Print0 Forced", []string{"--stats", "-0", "-y"}, "", "stats-silent.txt"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+			testos.RequireWrite(t, v, path.Join(dir, "a", "x"), strings.Repeat("x", 2000))
+			testos.RequireWrite(t, v, path.Join(dir, "c", "x"), "xyz")
+
+			_, stdout, stderr := vos.GetStdio(v)
+
+			args := append([]string{"-f", "x", "-f", "y"}, tc.args...)
+			err = execWithArgsInDir(dir, args...)
+			require.NoError(t, err)
+
+			gotErr, err := io.ReadAll(stderr)
+			require.NoError(t, err)
+			if tc.wantStderr != "" {
+				assertGolden(t, tc.wantStderr, normalizeOutput(string(gotErr), dir))
+			} else {
+				assert.Empty(t, gotErr)
+			}
+
+			gotOut, err := io.ReadAll(stdout)
+			require.NoError(t, err)
+			if tc.wantStdout != "" {
+				assertGolden(t, tc.wantStdout, normalizeOutput(string(gotOut), dir))
+			}
+		})
+
+		vos.ClearStdio(v)
+	}
+}
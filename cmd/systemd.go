@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	stdos "os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/echocrow/cleardir/internal/jobs"
+	"github.com/echocrow/cleardir/internal/systemd"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	os "github.com/echocrow/osa"
+	"github.com/spf13/cobra"
+)
+
+type systemdCmd struct {
+	cmd  *cobra.Command
+	opts systemdOpts
+}
+
+type systemdOpts struct {
+	cfg    string
+	job    string
+	user   bool
+	exec   string
+	outDir string
+}
+
+func newSystemdCmd() *systemdCmd {
+	sc := &systemdCmd{}
+	opts := &sc.opts
+
+	cmd := &cobra.Command{
+		Use:               "systemd",
+		Short:             "Integrate jobs with systemd",
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+	}
+
+	gen := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a service and timer unit for a job",
+		Long: heredoc.Doc(`
+			Generate prints a systemd service unit running a configured job once,
+			followed by a timer unit activating the service on the job's schedule.
+			The service may only write to the roots of the job.
+		`),
+		Example: indentHeredoc(`
+		  cleardir systemd generate --job downloads
+		  cleardir systemd generate --job downloads --user -o ~/.config/systemd/user
+		`),
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSystemdGenerate(cmd, opts)
+		},
+	}
+	gen.Flags().StringVarP(&opts.cfg, "config", "c", "", "specify the configuration file path")
+	gen.Flags().StringVarP(&opts.job, "job", "", "", "the name of the job to generate units for")
+	gen.Flags().BoolVarP(&opts.user, "user", "", false, "generate units for the per-user service manager")
+	gen.Flags().StringVarP(&opts.exec, "exec", "", "", flushHeredoc(`
+		the cleardir executable, as a path or a name to look up
+		in PATH (default this executable)
+	`))
+	gen.Flags().StringVarP(&opts.outDir, "output-dir", "o", "", "write the units into this directory instead of printing them")
+	_ = gen.MarkFlagRequired("job")
+	_ = gen.RegisterFlagCompletionFunc("job", completeJobs(&opts.cfg))
+	cmd.AddCommand(gen)
+
+	sc.cmd = cmd
+	return sc
+}
+
+func runSystemdGenerate(cmd *cobra.Command, opts *systemdOpts) error {
+	cfg, cfgPath, err := cleardir.ReadConfig(opts.cfg)
+	if err != nil {
+		return err
+	}
+	js, err := jobs.Parse(cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cfgPath, err)
+	}
+	j, ok := jobs.Find(js, opts.job)
+	if !ok {
+		return fmt.Errorf("unknown job %q", opts.job)
+	}
+	if j.Schedule == nil {
+		return fmt.Errorf("job %q has no schedule", j.Name)
+	}
+
+	exec := opts.exec
+	if exec == "" {
+		if exec, err = stdos.Executable(); err != nil {
+			return err
+		}
+	}
+	if !strings.ContainsRune(exec, filepath.Separator) {
+		if exec, err = osexec.LookPath(exec); err != nil {
+			return err
+		}
+	}
+	if exec, err = filepath.Abs(exec); err != nil {
+		return err
+	}
+	if cfgPath, err = filepath.Abs(cfgPath); err != nil {
+		return err
+	}
+
+	service, err := systemd.Service(j, systemd.Options{
+		Exec:   exec,
+		Config: cfgPath,
+		User:   opts.user,
+	})
+	if err != nil {
+		return err
+	}
+	timer, err := systemd.Timer(j)
+	if err != nil {
+		return err
+	}
+	units := []struct{ name, text string }{
+		{systemd.UnitName(j) + ".service", service},
+		{systemd.UnitName(j) + ".timer", timer},
+	}
+
+	if opts.outDir == "" {
+		for i, u := range units {
+			if i > 0 {
+				cmd.Println()
+			}
+			cmd.Printf("# %s\n%s", u.name, u.text)
+		}
+		return nil
+	}
+
+	cmd.SilenceUsage = true
+	info, err := os.Stat(opts.outDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("output path is not a directory")
+	}
+	for _, u := range units {
+		path := filepath.Join(opts.outDir, u.name)
+		if err := os.WriteFile(path, []byte(u.text), 0o644); err != nil {
+			return err
+		}
+		cmd.Println(path)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package cmd_test
+
+import (
+	"io"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/echocrow/osa/testos"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdSystemd(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	jobsCfg := heredoc.Doc(`
+		# cleardir config v2
+		[job nightly]
+		roots = $ROOT/a, $ROOT/my 100% dir
+		schedule = 30 3 13 * 5
+
+		[job often]
+		roots = /tmp/cache
+		schedule = @every 15m
+
+		[job manual]
+		roots = $ROOT/a
+	`)
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"System", []string{"--job", "nightly"}, "systemd-system.txt", false},
+		{"User", []string{"--job", "often", "--user"}, "systemd-user.txt", false},
+		{"Output Dir", []string{"--job", "often", "-o", "$ROOT"}, "systemd-output-dir.txt", false},
+		{"Output Dir Missing", []string{"--job", "often", "-o", "$ROOT/missing"}, "", true},
+		{"Unknown Exec", []string{"--job", "often", "--exec", "cleardir-missing"}, "", true},
+		{"No Schedule", []string{"--job", "manual"}, "", true},
+		{"Unknown Job", []string{"--job", "other"}, "", true},
+		{"Missing Job", nil, "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			cfgPath := path.Join(dir, "cfg")
+			testos.RequireWrite(t, v, cfgPath, strings.ReplaceAll(jobsCfg, "$ROOT", dir))
+
+			_, stdout, _ := vos.GetStdio(v)
+
+			args := []string{"systemd", "generate", "-c", cfgPath, "--exec", "/usr/bin/cleardir"}
+			for _, a := range tc.args {
+				args = append(args, strings.ReplaceAll(a, "$ROOT", dir))
+			}
+			err := execWithArgs(args...)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(stdout)
+			require.NoError(t, err)
+			if tc.name == "Output Dir" {
+				for _, name := range []string{"cleardir-often.service", "cleardir-often.timer"} {
+					unit, err := v.ReadFile(path.Join(dir, name))
+					require.NoError(t, err)
+					got = append(got, "# "+name+"\n"...)
+					got = append(got, unit...)
+				}
+			}
+			assertGolden(t, tc.want, normalizeOutput(string(got), dir))
+		})
+
+		vos.ClearStdio(v)
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/spf13/cobra"
+)
+
+// treeNode is a single entry of a rendered clear plan.
+type treeNode struct {
+	name     string
+	match    *cleardir.Match
+	children map[string]*treeNode
+}
+
+func newTreeNode(name string) *treeNode {
+	return &treeNode{name: name, children: map[string]*treeNode{}}
+}
+
+// buildTree arranges all matches of a plan into a tree relative to its root.
+//
+// Directories that are not clearable themselves are kept as plain nodes.
+func buildTree(plan rootPlan) *treeNode {
+	root := newTreeNode(plan.root)
+	for i := range plan.matches {
+		m := &plan.matches[i]
+		rel, err := filepath.Rel(plan.root, m.Path)
+		if err != nil {
+			continue
+		}
+		n := root
+		for _, name := range strings.Split(rel, string(filepath.Separator)) {
+			c, ok := n.children[name]
+			if !ok {
+				c = newTreeNode(name)
+				n.children[name] = c
+			}
+			n = c
+		}
+		n.match = m
+	}
+	return root
+}
+
+func (n *treeNode) isDir() bool {
+	return n.match == nil || n.match.Kind == cleardir.KindDir
+}
+
+func (n *treeNode) sortedChildren() []*treeNode {
+	children := make([]*treeNode, 0, len(n.children))
+	for _, c := range n.children {
+		children = append(children, c)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].name < children[j].name
+	})
+	return children
+}
+
+// count counts all clearable directories and files below n.
+func (n *treeNode) count() (dirs, files int) {
+	for _, c := range n.children {
+		if c.match != nil {
+			if c.isDir() {
+				dirs++
+			} else {
+				files++
+			}
+		}
+		d, f := c.count()
+		dirs += d
+		files += f
+	}
+	return
+}
+
+// renderTree renders n and its children as an indented tree.
+//
+// Clearable directories are collapsed. Nodes beyond maxDepth are collapsed
+// into their parent; use -1 for no limit.
+func renderTree(cmd *cobra.Command, pf pathFormatter, n *treeNode, maxDepth int) {
+	cmd.Println(pf.root(n.name + string(filepath.Separator)))
+	for _, c := range n.sortedChildren() {
+		renderTreeNode(cmd, pf, c, 0, maxDepth)
+	}
+}
+
+func renderTreeNode(cmd *cobra.Command, pf pathFormatter, n *treeNode, depth, maxDepth int) {
+	indent := strings.Repeat("  ", depth+1)
+	marker := ""
+	if n.match != nil {
+		marker = "- "
+	}
+	name := n.name
+	if n.isDir() {
+		name += string(filepath.Separator)
+	}
+	name = pf.name(name, n.isDir())
+
+	collapse := n.match != nil || (maxDepth >= 0 && depth >= maxDepth)
+	suffix := ""
+	if collapse {
+		suffix = countSuffix(n.count())
+	}
+
+	cmd.Printf("%s%s%s%s\n", indent, marker, name, suffix)
+	if collapse {
+		return
+	}
+	for _, c := range n.sortedChildren() {
+		renderTreeNode(cmd, pf, c, depth+1, maxDepth)
+	}
+}
+
+func countSuffix(dirs, files int) string {
+	parts := []string{}
+	if dirs > 0 {
+		parts = append(parts, plural(dirs, "dir"))
+	}
+	if files > 0 {
+		parts = append(parts, plural(files, "file"))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// treeReporter renders the plan as a tree once scanning has ended.
+type treeReporter struct {
+	textReporter
+	maxDepth int
+}
+
+func (treeReporter) match(string, cleardir.Match) {}
+
+func (r treeReporter) plan(plans []rootPlan) {
+	for _, p := range plans {
+		if len(p.matches) > 0 {
+			renderTree(r.cmd, r.pf, buildTree(p), r.maxDepth)
+		}
+	}
+	r.textReporter.plan(plans)
+}
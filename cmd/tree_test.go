@@ -0,0 +1,64 @@
+package cmd_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdTree(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{
+		"build": fsd{
+			"a": fsd{"f": nil, "g": nil},
+			"b": fsd{"c": fsd{}},
+			"f": nil,
+		},
+		"src": fsd{
+			"keep":  nil,
+			"empty": fsd{},
+			"deep": fsd{
+				"keep": nil,
+				"x":    fsd{"f": nil},
+			},
+		},
+		"f":    nil,
+		"keep": nil,
+	}
+
+	tests := []struct {
+		name   string
+		golden string
+		args   []string
+	}{
+		{"Tree", "tree.txt", []string{"--tree"}},
+		{"Tree Depth", "tree-depth.txt", []string{"--tree", "--tree-depth", "0"}},
+		{"Tree Output", "tree.txt", []string{"-o", "tree"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+
+			_, stdout, stderr := vos.GetStdio(v)
+
+			args := append([]string{"-f", "f", "-f", "g", "--dry"}, tc.args...)
+			err = execWithArgsInDir(dir, args...)
+			require.NoError(t, err)
+			assert.Empty(t, stderr)
+
+			out, err := io.ReadAll(stdout)
+			require.NoError(t, err)
+			got := normalizeOutput(string(out), dir)
+			assertGolden(t, tc.golden, got)
+		})
+
+		vos.ClearStdio(v)
+	}
+}
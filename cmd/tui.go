@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	stdos "os"
+
+	"github.com/echocrow/cleardir/internal/tui"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// runTUI lets the user browse and select matches of all plans in a
+// full-screen terminal UI, and returns the paths of all selected matches.
+//
+// The returned paths are nil if the user quit without deleting.
+func runTUI(cmd *cobra.Command, plans []rootPlan) ([]string, error) {
+	in, out, err := tuiTerminal(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := make([]tui.Root, len(plans))
+	for i, p := range plans {
+		roots[i] = tui.Root{Path: p.root, Matches: p.matches}
+	}
+	m := tui.New(roots, 80, 24)
+	if err := tui.Run(in, out, m); err != nil {
+		return nil, err
+	}
+	if m.Action() != tui.ActionDelete {
+		return nil, nil
+	}
+	return m.Selected(), nil
+}
+
+// tuiTerminal returns the terminal files behind the command's input and
+// output.
+func tuiTerminal(cmd *cobra.Command) (in, out *stdos.File, err error) {
+	in, out = termFile(cmd.InOrStdin()), termFile(cmd.OutOrStdout())
+	if in == nil || out == nil || !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return nil, nil, errors.New("\"--tui\" requires a terminal")
+	}
+	return in, out, nil
+}
+
+// tuiReporter only reports the plan summary, as the terminal UI lists all
+// matches itself.
+type tuiReporter struct {
+	textReporter
+}
+
+func (tuiReporter) match(string, cleardir.Match) {}
@@ -0,0 +1,32 @@
+package cmd_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdTUIErr(t *testing.T) {
+	_, reset := vos.Patch()
+	defer reset()
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"--tui"}, "requires a terminal"},
+		{[]string{"--tui", "-y"}, "cannot be combined"},
+		{[]string{"--tui", "--dry"}, "cannot be combined"},
+		{[]string{"--tui", "-i"}, "cannot be combined"},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			err := execWithArgs(tc.args...)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
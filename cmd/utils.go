@@ -8,18 +8,41 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// prompter reads answers to prompts from the command's input.
+//
+// A single prompter should be used for consecutive prompts, as it buffers
+// input.
+type prompter struct {
+	cmd *cobra.Command
+	r   *bufio.Reader
+}
+
+func newPrompter(cmd *cobra.Command) *prompter {
+	return &prompter{cmd, bufio.NewReader(cmd.InOrStdin())}
+}
+
+// ask prints msg followed by the given choices, and reads a trimmed,
+// lower-case answer.
+func (p *prompter) ask(msg, choices string) (string, error) {
+	p.cmd.Printf("%s [%s]: ", msg, choices)
+	res, err := p.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(strings.TrimSpace(res)), nil
+}
+
 func confirm(cmd *cobra.Command, msg string, attempts int, resolve bool) bool {
 	var res string
 	var err error
-	r := bufio.NewReader(cmd.InOrStdin())
+	p := newPrompter(cmd)
 	for ; attempts > 0; attempts-- {
-		cmd.Printf("%s [y/N]: ", msg)
 		if resolve {
+			cmd.Printf("%s [y/N]: ", msg)
 			res = "y"
 			cmd.Println(res)
 		} else {
-			res, err = r.ReadString('\n')
-			res = strings.ToLower(strings.TrimSpace(res))
+			res, err = p.ask(msg, "y/N")
 		}
 		if err != nil {
 			return false
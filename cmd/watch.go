@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/spf13/cobra"
+)
+
+type watchCmd struct {
+	cmd  *cobra.Command
+	opts watchOpts
+}
+
+type watchOpts struct {
+	cfg       string
+	trivials  []string
+	olderThan time.Duration
+	grace     time.Duration
+	debounce  time.Duration
+	jobs      int
+	verbose   bool
+	lock      lockOpts
+}
+
+func newWatchCmd() *watchCmd {
+	wc := &watchCmd{}
+	opts := &wc.opts
+
+	cmd := &cobra.Command{
+		Use:   "watch [PATH]",
+		Short: "Keep clearing a directory as its contents change",
+		Long: heredoc.Doc(`
+			Watch clears a directory, then keeps running and clears any files and
+			folders that become clearable later on. Only the folders affected by a
+			change are re-evaluated. Watch stops on SIGINT or SIGTERM.
+		`),
+		Example: indentHeredoc(`
+		  cleardir watch
+		  cleardir watch --older-than 10m some/path
+		`),
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeDir,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := ""
+			if len(args) > 0 {
+				root = args[0]
+			}
+			return runWatch(cmd, opts, root)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.cfg, "config", "c", "", "specify the configuration file path")
+	cmd.Flags().StringSliceVarP(&opts.trivials, "files", "f", nil, "list files that can be deleted safely")
+	cmd.Flags().DurationVarP(&opts.olderThan, "older-than", "", 0, flushHeredoc(`
+		only clear files and empty directories last modified
+		at least this long ago, e.g. "10m"
+	`))
+	cmd.Flags().DurationVarP(&opts.grace, "grace", "", cleardir.DefaultGrace, flushHeredoc(`
+		only clear directories last modified at least this long ago,
+		so that new directories may be filled first; use "0" for none
+	`))
+	cmd.Flags().DurationVarP(&opts.debounce, "debounce", "", time.Second, "wait this long for further changes before clearing")
+	cmd.Flags().IntVarP(&opts.jobs, "jobs", "j", 1, "read or remove up to this many entries concurrently")
+	cmd.Flags().BoolVarP(&opts.verbose, "verbose", "v", false, "also log each re-evaluated directory")
+	addLockFlags(cmd.Flags(), &opts.lock)
+
+	wc.cmd = cmd
+	return wc
+}
+
+func runWatch(cmd *cobra.Command, opts *watchOpts, rawRoot string) error {
+	cfg, _, err := cleardir.ReadConfig(opts.cfg)
+	if err != nil {
+		return err
+	}
+	root, err := filepath.Abs(rawRoot)
+	if err != nil {
+		return err
+	}
+	locker, err := opts.lock.locker()
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	lk, err := lockRoots(cmd.Context(), locker, []string{root})
+	if err != nil {
+		return err
+	}
+	defer lk.Unlock()
+
+	// Unlike a zero grace period of the watcher, "--grace 0" disables it.
+	grace := opts.grace
+	if grace == 0 {
+		grace = -1
+	}
+
+	logger := log.New(cmd.OutOrStdout(), "", log.LstdFlags)
+	w := &cleardir.Watcher{
+		Options: cleardir.Options{
+			Trivials: append(cfg.Clearables, opts.trivials...),
+			Jobs:     opts.jobs,
+			OnError: func(path string, err error) error {
+				logger.Printf("Skipped unreadable directory: %s", err)
+				return nil
+			},
+		},
+		MinAge:   opts.olderThan,
+		Grace:    grace,
+		Debounce: opts.debounce,
+		Remover: cleardir.Remover{
+			Jobs: opts.jobs,
+			OnRemove: func(path string, err error) {
+				if err != nil {
+					logger.Printf("Failed to remove: %s", err)
+					return
+				}
+				logger.Printf("Removed %s", path)
+			},
+		},
+	}
+	if opts.verbose {
+		w.OnScan = func(path string) {
+			logger.Printf("Scanning %s", path)
+		}
+	}
+
+	logger.Printf("Watching %s", root)
+	err = w.Watch(cmd.Context(), root)
+	if errors.Is(err, context.Canceled) {
+		logger.Printf("Stopped watching %s", root)
+		return nil
+	}
+	return err
+}
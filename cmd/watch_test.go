@@ -0,0 +1,67 @@
+package cmd_test
+
+import (
+	"context"
+	"io"
+	stdos "os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdWatch(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "config")
+	root := filepath.Join(dir, "root")
+	require.NoError(t, stdos.WriteFile(cfg, nil, 0o644))
+	require.NoError(t, stdos.MkdirAll(filepath.Join(root, "a"), 0o755))
+	require.NoError(t, stdos.WriteFile(filepath.Join(root, "keep"), nil, 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := &syncBuffer{}
+	c := newCmd()
+	c.SetOut(out)
+	c.SetErr(out)
+	c.SetArgs([]string{"watch", "-c", cfg, "-f", "x", "--debounce", "10ms", "--grace", "50ms", root})
+	errc := make(chan error, 1)
+	go func() { errc <- c.ExecuteContext(ctx) }()
+
+	isCleared := func(path string) func() bool {
+		return func() bool {
+			_, err := stdos.Stat(path)
+			return stdos.IsNotExist(err)
+		}
+	}
+	require.Eventually(t, isCleared(filepath.Join(root, "a")), time.Second, 5*time.Millisecond)
+
+	require.NoError(t, stdos.Mkdir(filepath.Join(root, "b"), 0o755))
+	require.NoError(t, stdos.WriteFile(filepath.Join(root, "b", "x"), nil, 0o644))
+	require.Eventually(t, isCleared(filepath.Join(root, "b")), time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-errc)
+	assert.FileExists(t, filepath.Join(root, "keep"))
+
+	got := out.String()
+	assert.Contains(t, got, "Watching "+root)
+	assert.Contains(t, got, "Removed "+filepath.Join(root, "a"))
+	assert.Contains(t, got, "Removed "+filepath.Join(root, "b", "x"))
+	assert.Contains(t, got, "Removed "+filepath.Join(root, "b"))
+	assert.Contains(t, got, "Stopped watching "+root)
+}
+
+func TestCmdWatchErr(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "config")
+	require.NoError(t, stdos.WriteFile(cfg, nil, 0o644))
+
+	c := newCmd()
+	c.SetOut(io.Discard)
+	c.SetErr(io.Discard)
+	c.SetArgs([]string{"watch", "-c", cfg, filepath.Join(dir, "missing")})
+	assert.Error(t, c.Execute())
+}
@@ -6,15 +6,18 @@ require (
 	github.com/MakeNowJust/heredoc/v2 v2.0.1
 	github.com/echocrow/fsnap v0.1.1
 	github.com/echocrow/osa v0.2.1
+	github.com/fsnotify/fsnotify v1.5.1
 	github.com/scylladb/go-set v1.0.2
 	github.com/spf13/cobra v1.2.1
+	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c // indirect
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
@@ -0,0 +1,218 @@
+// Package audit records removals to an append-only log of JSON lines.
+package audit
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	stdos "os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+)
+
+// Section is the config section kind of the audit log, as in "[audit]".
+const Section = "audit"
+
+// Events of records.
+const (
+	// EventRemove records the attempted removal of a single path.
+	EventRemove = "remove"
+	// EventRun records the outcome of a run.
+	EventRun = "run"
+)
+
+// Statuses of records.
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusAborted = "aborted"
+)
+
+// Context describes the invocation of a run.
+type Context struct {
+	// Run identifies the run among all runs of the log.
+	Run     string   `json:"run"`
+	User    string   `json:"user"`
+	Host    string   `json:"host"`
+	Cwd     string   `json:"cwd"`
+	Command []string `json:"command"`
+}
+
+// ParsePath reads the path of the log from the "[audit]" section of cfg, or
+// returns an empty path if no log is configured.
+func ParsePath(cfg cleardir.Config) (string, error) {
+	sec, ok := cfg.Section(Section)
+	if !ok {
+		return "", nil
+	}
+	path := ""
+	for k, v := range sec.Values {
+		switch k {
+		case "log":
+			if v != "" && !filepath.IsAbs(v) {
+				return "", fmt.Errorf("audit: log path %q is not absolute", v)
+			}
+			path = v
+		default:
+			return "", fmt.Errorf("audit: unknown key %q", k)
+		}
+	}
+	return path, nil
+}
+
+// NewContext describes the current process. Unknown details are left empty.
+func NewContext() Context {
+	c := Context{Command: stdos.Args}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err == nil {
+		c.Run = hex.EncodeToString(id[:])
+	}
+	if u, err := user.Current(); err == nil {
+		c.User = u.Username
+	} else {
+		c.User = stdos.Getenv("USER")
+	}
+	c.Host, _ = stdos.Hostname()
+	c.Cwd, _ = stdos.Getwd()
+	return c
+}
+
+// Record is a single entry of the log.
+type Record struct {
+	Time time.Time `json:"time"`
+	Context
+	Event string `json:"event"`
+	// Job is the name of the job of the run, if any.
+	Job string `json:"job,omitempty"`
+	// Roots lists the roots of a run, for EventRun.
+	Roots []string `json:"roots,omitempty"`
+	// Root is the root of a removed path, for EventRemove.
+	Root string `json:"root,omitempty"`
+	// Path is the removed path, for EventRemove.
+	Path string `json:"path,omitempty"`
+	// Kind is the kind of the removed path, for EventRemove.
+	Kind string `json:"kind,omitempty"`
+	// Rule is the trivial file name that matched a removed file.
+	Rule string `json:"rule,omitempty"`
+	// Size is the size of a removed file in bytes for EventRemove, or of all
+	// removed files for EventRun.
+	Size int64 `json:"size"`
+	// Count is the number of removed paths, for EventRun.
+	Count  int    `json:"count,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Writer appends records to a log file.
+//
+// Each record is written with a single write while holding an exclusive lock
+// on the log, and synced to disk before returning, so that concurrent runs
+// never interleave their records, and no recorded removal is lost to a crash.
+// Calls to a Writer must not be concurrent.
+type Writer struct {
+	f *stdos.File
+}
+
+// Open opens the log file at path for appending, creating it and its parent
+// directories if necessary.
+func Open(path string) (*Writer, error) {
+	if err := stdos.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := stdos.OpenFile(path, stdos.O_WRONLY|stdos.O_APPEND|stdos.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{f: f}, nil
+}
+
+// Write appends r to the log, and syncs it to disk.
+func (w *Writer) Write(r Record) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	r.Time = r.Time.UTC()
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if err := lockFile(w.f); err != nil {
+		return err
+	}
+	_, err = w.f.Write(b)
+	if err == nil {
+		err = w.f.Sync()
+	}
+	if uerr := unlockFile(w.f); err == nil {
+		err = uerr
+	}
+	return err
+}
+
+// Close closes the log file.
+func (w *Writer) Close() error {
+	return w.f.Close()
+}
+
+// Filter selects records of a log.
+type Filter struct {
+	// Since, if set, selects records at or after this time.
+	Since time.Time
+	// Until, if set, selects records before this time.
+	Until time.Time
+	// Path, if set, selects removals of this path or of paths inside it, and
+	// runs with roots overlapping it.
+	Path string
+}
+
+// Match reports whether f selects r.
+func (f Filter) Match(r Record) bool {
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !r.Time.Before(f.Until) {
+		return false
+	}
+	if f.Path == "" {
+		return true
+	}
+	if r.Event == EventRun {
+		for _, root := range r.Roots {
+			if root == f.Path || cleardir.IsNested(root, f.Path) || cleardir.IsNested(f.Path, root) {
+				return true
+			}
+		}
+		return false
+	}
+	return r.Path == f.Path || cleardir.IsNested(f.Path, r.Path)
+}
+
+// Read reads all records of a log selected by f, and calls fn with each.
+func Read(r io.Reader, f Filter, fn func(Record) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		if f.Match(rec) {
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+	}
+	return sc.Err()
+}
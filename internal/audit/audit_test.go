@@ -0,0 +1,125 @@
+package audit_test
+
+import (
+	"bytes"
+	"fmt"
+	stdos "os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/echocrow/cleardir/internal/audit"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePath(t *testing.T) {
+	cfg := cleardir.Config{Sections: []cleardir.Section{
+		{Kind: audit.Section, Values: map[string]string{"log": "/var/log/cleardir.jsonl"}},
+	}}
+	path, err := audit.ParsePath(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "/var/log/cleardir.jsonl", path)
+
+	path, err = audit.ParsePath(cleardir.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, "", path)
+
+	for _, values := range []map[string]string{{"log": "rel.jsonl"}, {"file": "/a"}} {
+		cfg := cleardir.Config{Sections: []cleardir.Section{{Kind: audit.Section, Values: values}}}
+		_, err := audit.ParsePath(cfg)
+		assert.Error(t, err)
+	}
+}
+
+func TestWriterConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+	const writers, records = 4, 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w, err := audit.Open(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			for j := 0; j < records; j++ {
+				// Long paths make torn writes more likely.
+				p := fmt.Sprintf("/%d/%s/%d", i, strings.Repeat("x", 200), j)
+				if err := w.Write(audit.Record{Event: audit.EventRemove, Path: p, Status: audit.StatusOK}); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- w.Close()
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	f, err := stdos.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	n := 0
+	err = audit.Read(f, audit.Filter{}, func(r audit.Record) error {
+		n++
+		assert.False(t, r.Time.IsZero())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, writers*records, n)
+}
+
+func TestRead(t *testing.T) {
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	var log bytes.Buffer
+	for _, line := range []string{
+		`{"time":"2026-10-18T23:00:00Z","event":"remove","path":"/srv/a/x","status":"ok"}`,
+		`{"time":"2026-10-19T10:00:00Z","event":"remove","path":"/srv/a/y","status":"ok"}`,
+		`{"time":"2026-10-19T10:00:00Z","event":"remove","path":"/srv/ab","status":"ok"}`,
+		`{"time":"2026-10-19T10:00:01Z","event":"run","roots":["/srv"],"status":"ok"}`,
+		``,
+		`{"time":"2026-10-20T00:00:00Z","event":"remove","path":"/srv/a","status":"failed"}`,
+	} {
+		log.WriteString(line + "\n")
+	}
+
+	tests := []struct {
+		name   string
+		filter audit.Filter
+		want   []string
+	}{
+		{"All", audit.Filter{}, []string{"/srv/a/x", "/srv/a/y", "/srv/ab", "run", "/srv/a"}},
+		{"Day", audit.Filter{Since: day, Until: day.AddDate(0, 0, 1)}, []string{"/srv/a/y", "/srv/ab", "run"}},
+		{"Path", audit.Filter{Path: "/srv/a"}, []string{"/srv/a/x", "/srv/a/y", "run", "/srv/a"}},
+		{"Nested Path", audit.Filter{Path: "/srv/a/y"}, []string{"/srv/a/y", "run"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := []string{}
+			err := audit.Read(bytes.NewReader(log.Bytes()), tc.filter, func(r audit.Record) error {
+				if r.Event == audit.EventRun {
+					got = append(got, "run")
+				} else {
+					got = append(got, r.Path)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	err := audit.Read(strings.NewReader("{}\nnope\n"), audit.Filter{}, func(audit.Record) error { return nil })
+	assert.EqualError(t, err, "line 2: invalid character 'o' in literal null (expecting 'u')")
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package audit
+
+import (
+	stdos "os"
+	"syscall"
+)
+
+func lockFile(f *stdos.File) error {
+	return flock(f, syscall.LOCK_EX)
+}
+
+func unlockFile(f *stdos.File) error {
+	return flock(f, syscall.LOCK_UN)
+}
+
+func flock(f *stdos.File, how int) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), how)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
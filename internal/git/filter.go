@@ -0,0 +1,95 @@
+package git
+
+import (
+	"path/filepath"
+	"sync"
+
+	os "github.com/echocrow/osa"
+)
+
+// Filter protects the contents of git work trees from clearing. Its Keep and
+// Skip methods fit cleardir.Options.
+//
+// Entries tracked in the index of their work tree are kept, and git
+// directories are skipped. Work trees nested below the roots, such as
+// submodules or independent clones, are discovered while scanning.
+type Filter struct {
+	// IgnoredOnly additionally keeps all untracked entries that are not
+	// ignored, so that only ignored entries may be cleared.
+	IgnoredOnly bool
+
+	mu    sync.Mutex
+	repos []*Repo
+}
+
+// NewFilter creates a Filter for scans of roots.
+func NewFilter(roots []string, ignoredOnly bool) (*Filter, error) {
+	f := &Filter{IgnoredOnly: ignoredOnly}
+	for _, root := range roots {
+		r, err := Find(root)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			f.add(r)
+		}
+	}
+	return f, nil
+}
+
+func (f *Filter) add(r *Repo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, o := range f.repos {
+		if o.WorkTree == r.WorkTree {
+			return
+		}
+	}
+	f.repos = append(f.repos, r)
+}
+
+// repo returns the innermost known work tree containing path, along with the
+// path relative to it.
+func (f *Filter) repo(path string) (*Repo, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var found *Repo
+	foundRel := ""
+	for _, r := range f.repos {
+		rel, ok := r.Rel(path)
+		if ok && (found == nil || len(r.WorkTree) > len(found.WorkTree)) {
+			found, foundRel = r, rel
+		}
+	}
+	return found, foundRel
+}
+
+// Keep reports whether the entry at path must be kept.
+func (f *Filter) Keep(path string, isDir bool) bool {
+	r, rel := f.repo(path)
+	if r == nil {
+		return false
+	}
+	if r.Tracked(rel) {
+		return true
+	}
+	return f.IgnoredOnly && !r.Ignored(rel, isDir)
+}
+
+// Skip reports whether the directory at path is a git directory. It also
+// records any work tree at path for subsequent calls to Keep.
+func (f *Filter) Skip(path string) bool {
+	if filepath.Base(path) == DirName {
+		return true
+	}
+	if _, err := os.Stat(filepath.Join(path, DirName)); err != nil {
+		return false
+	}
+	r, err := Open(path)
+	if err != nil {
+		// Leave work trees alone that cannot be read.
+		return true
+	}
+	f.add(r)
+	return false
+}
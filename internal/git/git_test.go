@@ -0,0 +1,197 @@
+package git_test
+
+import (
+	stdos "os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/echocrow/cleardir/internal/git"
+	"github.com/echocrow/fsnap/dirsnap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fsd = dirsnap.Dirs
+
+// newRepo creates a work tree with files, and returns its path.
+func newRepo(t *testing.T, files fsd) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	// Keep any user or system config out of tests.
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	runGit(t, dir, "init", "-q")
+	require.NoError(t, files.Write(dir))
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	out, err := c.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func writeFile(t *testing.T, name, content string) {
+	require.NoError(t, stdos.MkdirAll(filepath.Dir(name), 0o755))
+	require.NoError(t, stdos.WriteFile(name, []byte(content), 0o644))
+}
+
+func TestFind(t *testing.T) {
+	dir := newRepo(t, fsd{
+		"a":     fsd{".gitkeep": nil, "b": fsd{"file": nil}},
+		"c":     fsd{"new": nil},
+		"empty": fsd{},
+	})
+	runGit(t, dir, "add", "a")
+	runGit(t, dir, "update-index", "--add", "--cacheinfo", "160000,"+strings.Repeat("1", 40)+",sub")
+
+	for _, version := range []int{3, 4, 2} {
+		t.Run("Version "+strconv.Itoa(version), func(t *testing.T) {
+			// Intents to add require extended flags of version 3.
+			if version >= 3 {
+				runGit(t, dir, "add", "-N", "c/new")
+			} else {
+				runGit(t, dir, "rm", "-q", "--cached", "--ignore-unmatch", "c/new")
+			}
+			runGit(t, dir, "update-index", "--index-version", strconv.Itoa(version))
+
+			r, err := git.Find(filepath.Join(dir, "a", "b"))
+			require.NoError(t, err)
+			require.NotNil(t, r)
+			assert.Equal(t, dir, r.WorkTree)
+			assert.Equal(t, filepath.Join(dir, ".git"), r.GitDir)
+
+			assert.True(t, r.Tracked("a/.gitkeep"))
+			assert.True(t, r.Tracked("a/b/file"))
+			assert.True(t, r.Tracked("sub"))
+			assert.Equal(t, version >= 3, r.Tracked("c/new"))
+			assert.False(t, r.Tracked("a"))
+			assert.False(t, r.Tracked("empty"))
+		})
+	}
+}
+
+func TestFindOutside(t *testing.T) {
+	dir := t.TempDir()
+	r, err := git.Find(dir)
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+
+	_, err = git.Find(filepath.Join(dir, ".git", "refs"))
+	assert.Error(t, err)
+}
+
+func TestFindLinkedWorkTree(t *testing.T) {
+	dir := newRepo(t, fsd{"tracked": nil})
+	runGit(t, dir, "add", "tracked")
+	runGit(t, dir, "-c", "user.name=t", "-c", "user.email=t@t", "commit", "-qm", "init")
+	wt := filepath.Join(t.TempDir(), "wt")
+	runGit(t, dir, "worktree", "add", "-q", wt)
+
+	r, err := git.Find(wt)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, wt, r.WorkTree)
+	assert.True(t, r.Tracked("tracked"))
+}
+
+func TestIgnored(t *testing.T) {
+	dir := newRepo(t, fsd{
+		"build": fsd{"out": fsd{"x": nil}},
+		"docs":  fsd{"a": fsd{"b.tmp": nil}, "c.tmp": nil, "keep.log": nil},
+		"src":   fsd{"build": nil, "debug.log": nil, "vendor": fsd{"x": nil}},
+		"logs":  fsd{"x": nil},
+		"lib":   fsd{"x": nil, "y": nil},
+		"t":     fsd{"a.o": nil},
+	})
+	writeFile(t, filepath.Join(dir, ".gitignore"), "# comment\n*.log\n!keep.log\n/build/\ndocs/**/*.tmp\nlogs/**\n*.[!c]\n")
+	writeFile(t, filepath.Join(dir, "src", ".gitignore"), "vendor/\n!debug.log\n")
+	writeFile(t, filepath.Join(dir, "lib", ".gitignore"), "*\n!y\n")
+	writeFile(t, filepath.Join(dir, ".git", "info", "exclude"), "*.o\n")
+
+	r, err := git.Find(dir)
+	require.NoError(t, err)
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"build", true, true},
+		{"build/out/x", false, true},
+		{"src/build", false, false},
+		{"docs/keep.log", false, false},
+		{"docs/a/b.tmp", false, true},
+		{"docs/c.tmp", false, true},
+		{"src/debug.log", false, false},
+		{"src/vendor", true, true},
+		{"src/vendor/x", false, true},
+		{"logs", true, false},
+		{"logs/x", false, true},
+		{"lib/x", false, true},
+		{"lib/y", false, false},
+		{"t/a.o", false, true},
+		{"docs", true, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.rel, func(t *testing.T) {
+			assert.Equal(t, tc.want, r.Ignored(tc.rel, tc.isDir))
+
+			// Agree with git itself.
+			c := exec.Command("git", "check-ignore", "-q", "--no-index", tc.rel)
+			c.Dir = dir
+			err := c.Run()
+			var ee *exec.ExitError
+			if err != nil {
+				require.ErrorAs(t, err, &ee)
+			}
+			assert.Equal(t, tc.want, err == nil, "git check-ignore")
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	dir := newRepo(t, fsd{
+		"a":      fsd{".gitkeep": nil},
+		"junk":   fsd{".DS_Store": nil},
+		"tmp":    fsd{".DS_Store": nil},
+		"nested": fsd{"tracked": nil},
+	})
+	writeFile(t, filepath.Join(dir, ".gitignore"), "tmp/\n")
+	runGit(t, dir, "add", "a", ".gitignore")
+	runGit(t, filepath.Join(dir, "nested"), "init", "-q")
+	runGit(t, filepath.Join(dir, "nested"), "add", "tracked")
+
+	f, err := git.NewFilter([]string{dir}, false)
+	require.NoError(t, err)
+	assert.True(t, f.Skip(filepath.Join(dir, ".git")))
+	assert.True(t, f.Keep(filepath.Join(dir, "a", ".gitkeep"), false))
+	assert.False(t, f.Keep(filepath.Join(dir, "a"), true))
+	assert.False(t, f.Keep(filepath.Join(dir, "junk", ".DS_Store"), false))
+	assert.False(t, f.Keep(filepath.Join(dir, "nested", "tracked"), false))
+
+	// Nested work trees are discovered while scanning.
+	assert.False(t, f.Skip(filepath.Join(dir, "nested")))
+	assert.True(t, f.Keep(filepath.Join(dir, "nested", "tracked"), false))
+
+	f, err = git.NewFilter([]string{dir}, true)
+	require.NoError(t, err)
+	assert.True(t, f.Keep(filepath.Join(dir, "junk", ".DS_Store"), false))
+	assert.False(t, f.Keep(filepath.Join(dir, "tmp", ".DS_Store"), false))
+	assert.False(t, f.Keep(filepath.Join(dir, "tmp"), true))
+
+	// Paths outside of work trees are not protected.
+	outside := t.TempDir()
+	f, err = git.NewFilter([]string{outside}, true)
+	require.NoError(t, err)
+	assert.False(t, f.Keep(filepath.Join(outside, "x"), false))
+}
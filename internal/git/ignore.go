@@ -0,0 +1,129 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strings"
+)
+
+// pattern is a single pattern of an ignore file.
+type pattern struct {
+	// segs holds the slash-separated segments of the pattern, where "**"
+	// matches any number of segments.
+	segs []string
+	// base is the slash-separated directory of the ignore file relative to the
+	// work tree, or "" for the work tree itself.
+	base string
+	// anchored reports whether the pattern is matched against the full path
+	// below base, rather than against the name of entries at any depth.
+	anchored bool
+	// negate reports whether the pattern re-includes matched entries.
+	negate bool
+	// dirOnly reports whether the pattern only matches directories.
+	dirOnly bool
+}
+
+// parseIgnore parses the patterns of an ignore file located in base.
+func parseIgnore(b []byte, base string) []pattern {
+	patterns := []pattern{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := trimTrailingSpace(strings.TrimSuffix(sc.Text(), "\r"))
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		p := pattern{base: base}
+		if line[0] == '!' {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// Patterns with a leading or inner slash are relative to base.
+		p.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		// Go patterns negate character classes via "^" rather than "!".
+		line = strings.ReplaceAll(line, "[!", "[^")
+		p.segs = strings.Split(line, "/")
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// trimTrailingSpace removes trailing spaces that are not escaped.
+func trimTrailingSpace(s string) string {
+	for strings.HasSuffix(s, " ") && !strings.HasSuffix(s, `\ `) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// match reports whether the pattern matches the entry at the slash-separated
+// path rel relative to the work tree.
+func (p pattern) match(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if p.base != "" {
+		if !strings.HasPrefix(rel, p.base+"/") {
+			return false
+		}
+		rel = rel[len(p.base)+1:]
+	}
+	if !p.anchored {
+		ok, _ := path.Match(p.segs[0], path.Base(rel))
+		return ok
+	}
+	return matchSegs(p.segs, strings.Split(rel, "/"))
+}
+
+// matchSegs matches path segments against pattern segments.
+func matchSegs(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			if len(rest) == 0 {
+				// A trailing "**" matches everything inside, but not the
+				// directory itself.
+				return len(segs) > 0
+			}
+			for i := 0; i <= len(segs); i++ {
+				if matchSegs(rest, segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], segs[0]); !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+// ignoreList is an ordered list of patterns, where later patterns take
+// precedence.
+type ignoreList []pattern
+
+// ignored reports whether the last pattern matching rel ignores it, and
+// whether any pattern matched at all.
+func (l ignoreList) ignored(rel string, isDir bool) (ignored, matched bool) {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].match(rel, isDir) {
+			return !l[i].negate, true
+		}
+	}
+	return false, false
+}
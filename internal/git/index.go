@@ -0,0 +1,131 @@
+package git
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	indexSignature = "DIRC"
+	// indexEntryBase is the size of the fixed fields of an index entry before
+	// the object ID: ctime, mtime, dev, ino, mode, uid, gid, and size.
+	indexEntryBase = 40
+	// modeTypeMask masks the object type of an index entry mode.
+	modeTypeMask = 0o170000
+	// modeGitlink is the object type of submodules.
+	modeGitlink = 0o160000
+	// modeDir is the object type of directories of sparse indexes.
+	modeDir = 0o040000
+
+	flagExtended  = 0x4000
+	flagNameMask  = 0x0fff
+	sha1Size      = 20
+	sha256Size    = 32
+	entryPadAlign = 8
+)
+
+var errIndexTruncated = errors.New("truncated index")
+
+// indexEntry is a single path of an index.
+type indexEntry struct {
+	// Path is the slash-separated path relative to the work tree, without a
+	// trailing slash.
+	Path string
+	// Dir reports whether the entry is a submodule, or a directory of a sparse
+	// index.
+	Dir bool
+}
+
+// parseIndex parses the entries of a git index file in version 2, 3, or 4.
+//
+// Extensions are ignored.
+func parseIndex(b []byte, hashSize int) ([]indexEntry, error) {
+	if len(b) < 12 || string(b[:4]) != indexSignature {
+		return nil, errors.New("not a git index")
+	}
+	version := binary.BigEndian.Uint32(b[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported index version %d", version)
+	}
+	count := binary.BigEndian.Uint32(b[8:12])
+
+	entries := make([]indexEntry, 0, count)
+	prev := ""
+	off := 12
+	for i := uint32(0); i < count; i++ {
+		start := off
+		fixed := indexEntryBase + hashSize + 2
+		if len(b) < off+fixed {
+			return nil, errIndexTruncated
+		}
+		mode := binary.BigEndian.Uint32(b[off+24 : off+28])
+		flags := binary.BigEndian.Uint16(b[off+indexEntryBase+hashSize : off+fixed])
+		off += fixed
+		if version >= 3 && flags&flagExtended != 0 {
+			off += 2
+		}
+		if off > len(b) {
+			return nil, errIndexTruncated
+		}
+
+		var name string
+		if version == 4 {
+			// Names are prefix-compressed against the previous name.
+			strip, n := decodeVarint(b[off:])
+			if n == 0 || strip > len(prev) {
+				return nil, errIndexTruncated
+			}
+			off += n
+			end := bytes.IndexByte(b[off:], 0)
+			if end < 0 {
+				return nil, errIndexTruncated
+			}
+			name = prev[:len(prev)-strip] + string(b[off:off+end])
+			off += end + 1
+		} else {
+			end := bytes.IndexByte(b[off:], 0)
+			if end < 0 {
+				return nil, errIndexTruncated
+			}
+			if l := int(flags & flagNameMask); l < flagNameMask && l != end {
+				return nil, fmt.Errorf("invalid index entry %q", b[off:off+end])
+			}
+			name = string(b[off : off+end])
+			off += end + 1
+			// Entries are padded with NULs to a multiple of eight bytes.
+			if pad := (off - start) % entryPadAlign; pad != 0 {
+				off += entryPadAlign - pad
+			}
+		}
+		prev = name
+
+		typ := mode & modeTypeMask
+		e := indexEntry{Path: name, Dir: typ == modeGitlink || typ == modeDir}
+		if len(e.Path) > 0 && e.Path[len(e.Path)-1] == '/' {
+			e.Path, e.Dir = e.Path[:len(e.Path)-1], true
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// decodeVarint decodes a git offset varint, and returns it along with the
+// number of bytes read, or 0 if b is truncated.
+//
+// Unlike base-128 varints, each continuation adds one to the value, so that
+// each value has exactly one encoding.
+func decodeVarint(b []byte) (int, int) {
+	val := 0
+	for i, c := range b {
+		if i > 0 {
+			val++
+		}
+		val = val<<7 | int(c&0x7f)
+		if c&0x80 == 0 {
+			return val, i + 1
+		}
+	}
+	return 0, 0
+}
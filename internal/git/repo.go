@@ -0,0 +1,220 @@
+// Package git reads the state of git work trees without running git.
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	os "github.com/echocrow/osa"
+)
+
+// DirName is the name of the git directory inside a work tree.
+const DirName = ".git"
+
+// Repo is a git work tree along with its index.
+type Repo struct {
+	// WorkTree is the top-level directory of the work tree.
+	WorkTree string
+	// GitDir is the git directory of the work tree.
+	GitDir string
+
+	tracked map[string]bool
+	exclude ignoreList
+
+	mu      sync.Mutex
+	ignores map[string]ignoreList
+}
+
+// Find finds the work tree containing the directory at path, and reads its
+// index. It returns nil if path is not located inside any work tree.
+//
+// Paths inside git directories are rejected, as are bare repositories.
+func Find(dir string) (*Repo, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, el := range strings.Split(filepath.ToSlash(dir), "/") {
+		if el == DirName {
+			return nil, fmt.Errorf("%s is inside a git directory", dir)
+		}
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, DirName)); err == nil {
+			return Open(dir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Open reads the work tree at dir, whose git directory is dir/.git or is
+// referenced by a dir/.git file, as in linked work trees and submodules.
+func Open(dir string) (*Repo, error) {
+	gitDir, err := resolveGitDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Repo{
+		WorkTree: dir,
+		GitDir:   gitDir,
+		tracked:  map[string]bool{},
+		ignores:  map[string]ignoreList{},
+	}
+	common := commonDir(gitDir)
+
+	b, err := os.ReadFile(filepath.Join(gitDir, "index"))
+	if err == nil {
+		entries, err := parseIndex(b, hashSize(common))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Join(gitDir, "index"), err)
+		}
+		for _, e := range entries {
+			r.tracked[e.Path] = true
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if b, err := os.ReadFile(filepath.Join(common, "info", "exclude")); err == nil {
+		r.exclude = parseIgnore(b, "")
+	}
+	return r, nil
+}
+
+// resolveGitDir returns the git directory of the work tree at dir.
+func resolveGitDir(dir string) (string, error) {
+	gitPath := filepath.Join(dir, DirName)
+	info, err := os.Stat(gitPath)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return gitPath, nil
+	}
+	b, err := os.ReadFile(gitPath)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(b))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", fmt.Errorf("%s: invalid gitfile", gitPath)
+	}
+	gitDir := filepath.FromSlash(strings.TrimSpace(strings.TrimPrefix(line, "gitdir:")))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return gitDir, nil
+}
+
+// commonDir returns the directory holding the config and shared state of
+// gitDir, which differs from gitDir for linked work trees.
+func commonDir(gitDir string) string {
+	b, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	common := filepath.FromSlash(strings.TrimSpace(string(b)))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+	return common
+}
+
+// hashSize returns the size of object IDs of the repository at gitDir.
+func hashSize(gitDir string) int {
+	b, err := os.ReadFile(filepath.Join(gitDir, "config"))
+	if err != nil {
+		return sha1Size
+	}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		k, v, ok := cut(sc.Text(), "=")
+		if ok && strings.EqualFold(strings.TrimSpace(k), "objectformat") && strings.TrimSpace(v) == "sha256" {
+			return sha256Size
+		}
+	}
+	return sha1Size
+}
+
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// Rel returns the slash-separated path of p relative to the work tree, and
+// whether p is located inside the work tree at all.
+func (r *Repo) Rel(p string) (string, bool) {
+	rel, err := filepath.Rel(r.WorkTree, p)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// Tracked reports whether the index holds the file, submodule, or sparse
+// directory at the slash-separated path rel.
+func (r *Repo) Tracked(rel string) bool {
+	return r.tracked[rel]
+}
+
+// Ignored reports whether the entry at the slash-separated path rel is
+// ignored via ".gitignore" files or "info/exclude", either itself or via any
+// of its parent directories. It does not consider whether rel is tracked.
+//
+// Ignored may be called concurrently.
+func (r *Repo) Ignored(rel string, isDir bool) bool {
+	segs := strings.Split(rel, "/")
+	for i := 1; i < len(segs); i++ {
+		if r.ignoredEntry(strings.Join(segs[:i], "/"), true) {
+			return true
+		}
+	}
+	return r.ignoredEntry(rel, isDir)
+}
+
+// ignoredEntry reports whether rel itself is ignored, regardless of its
+// parent directories.
+func (r *Repo) ignoredEntry(rel string, isDir bool) bool {
+	// Ignore files of deeper directories take precedence.
+	dirs := []string{""}
+	if d := path.Dir(rel); d != "." {
+		segs := strings.Split(d, "/")
+		for i := range segs {
+			dirs = append(dirs, strings.Join(segs[:i+1], "/"))
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if ignored, ok := r.ignoreFile(dirs[i]).ignored(rel, isDir); ok {
+			return ignored
+		}
+	}
+	ignored, _ := r.exclude.ignored(rel, isDir)
+	return ignored
+}
+
+// ignoreFile returns the patterns of the ".gitignore" file of the
+// slash-separated directory dir.
+func (r *Repo) ignoreFile(dir string) ignoreList {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if l, ok := r.ignores[dir]; ok {
+		return l
+	}
+	var l ignoreList
+	if b, err := os.ReadFile(filepath.Join(r.WorkTree, filepath.FromSlash(dir), ".gitignore")); err == nil {
+		l = parseIgnore(b, dir)
+	}
+	r.ignores[dir] = l
+	return l
+}
@@ -0,0 +1,179 @@
+// Package hooks runs user-configured commands around clearing runs.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	stdos "os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+)
+
+// Section is the config section kind of hooks, as in "[hooks]".
+const Section = "hooks"
+
+// Names of all hooks.
+const (
+	// PreScan runs before scanning any roots.
+	PreScan = "pre-scan"
+	// PreRemove runs before removing anything, and receives the plan. It
+	// vetoes the removal by failing.
+	PreRemove = "pre-remove"
+	// PostRemove runs after removing, and receives the results.
+	PostRemove = "post-remove"
+	// OnError runs when a run fails.
+	OnError = "on-error"
+)
+
+// DefaultTimeout is how long hooks may run by default.
+const DefaultTimeout = time.Minute
+
+// Hooks is a set of configured hook commands.
+type Hooks struct {
+	// Commands maps hook names to shell commands.
+	Commands map[string]string
+	// Timeout is how long each hook may run before it is killed.
+	Timeout time.Duration
+	// Output receives the standard output and error of all hooks. It
+	// discards them if nil.
+	Output io.Writer
+}
+
+// Env describes a run to a hook.
+type Env struct {
+	// Roots lists all roots of the run.
+	Roots []string
+	// Job is the name of the job of the run, if any.
+	Job string
+	// Count is the number of planned items for PreRemove, or of removed items
+	// otherwise.
+	Count int
+	// Bytes is the total size of files planned for removal for PreRemove, or
+	// of removed files otherwise.
+	Bytes int64
+	// Err is the error of a failed run, for OnError.
+	Err error
+}
+
+// Error describes a failed hook.
+type Error struct {
+	Hook string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s hook failed: %s", e.Hook, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Parse reads all hooks from the "[hooks]" section of cfg.
+//
+// The section maps hook names to shell commands, and may set a "timeout" that
+// applies to each hook, such as "30s".
+func Parse(cfg cleardir.Config) (Hooks, error) {
+	h := Hooks{Commands: map[string]string{}, Timeout: DefaultTimeout}
+	sec, ok := cfg.Section(Section)
+	if !ok {
+		return h, nil
+	}
+	for k, v := range sec.Values {
+		switch k {
+		case PreScan, PreRemove, PostRemove, OnError:
+			if v != "" {
+				h.Commands[k] = v
+			}
+		case "timeout":
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return h, fmt.Errorf("hooks: invalid timeout %q", v)
+			}
+			h.Timeout = d
+		default:
+			return h, fmt.Errorf("hooks: unknown hook %q", k)
+		}
+	}
+	return h, nil
+}
+
+// Has reports whether the named hook is configured.
+func (h Hooks) Has(name string) bool {
+	return h.Commands[name] != ""
+}
+
+// Run runs the named hook with stdin as its standard input, if the hook is
+// configured.
+//
+// The hook runs via the shell, with the run described by environment
+// variables prefixed "CLEARDIR_". A hook that exits with a non-zero status,
+// or that runs for longer than the timeout, fails with an Error. Hooks are
+// killed along with any processes they started once they time out or ctx is
+// canceled.
+func (h Hooks) Run(ctx context.Context, name string, env Env, stdin []byte) error {
+	line := h.Commands[name]
+	if line == "" {
+		return nil
+	}
+	out := h.Output
+	if out == nil {
+		out = io.Discard
+	}
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	c := shellCommand(line)
+	c.Env = append(stdos.Environ(), env.vars(name)...)
+	c.Stdin = bytes.NewReader(stdin)
+	c.Stdout, c.Stderr = out, out
+	if err := c.Start(); err != nil {
+		return &Error{name, err}
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.Wait() }()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			return &Error{name, err}
+		}
+		return nil
+	case <-timer.C:
+		_ = killCommand(c)
+		<-done
+		return &Error{name, fmt.Errorf("timed out after %s", timeout)}
+	case <-ctx.Done():
+		_ = killCommand(c)
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (e Env) vars(name string) []string {
+	root := ""
+	if len(e.Roots) > 0 {
+		root = e.Roots[0]
+	}
+	vars := []string{
+		"CLEARDIR_HOOK=" + name,
+		"CLEARDIR_ROOT=" + root,
+		"CLEARDIR_ROOTS=" + strings.Join(e.Roots, "\n"),
+		"CLEARDIR_JOB=" + e.Job,
+		"CLEARDIR_COUNT=" + strconv.Itoa(e.Count),
+		"CLEARDIR_BYTES=" + strconv.FormatInt(e.Bytes, 10),
+	}
+	if e.Err != nil {
+		vars = append(vars, "CLEARDIR_ERROR="+e.Err.Error())
+	}
+	return vars
+}
@@ -0,0 +1,129 @@
+package hooks_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	stdos "os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/echocrow/cleardir/internal/hooks"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript writes an executable shell script, and returns its path.
+func writeScript(t *testing.T, body string) string {
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	err := stdos.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755)
+	require.NoError(t, err)
+	return path
+}
+
+func TestParse(t *testing.T) {
+	cfg := cleardir.Config{Sections: []cleardir.Section{
+		{Kind: hooks.Section, Values: map[string]string{
+			hooks.PreScan:    "pause-sync",
+			hooks.PostRemove: "resume-sync",
+			hooks.OnError:    "",
+			"timeout":        "5s",
+		}},
+	}}
+	h, err := hooks.Parse(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		hooks.PreScan:    "pause-sync",
+		hooks.PostRemove: "resume-sync",
+	}, h.Commands)
+	assert.Equal(t, 5*time.Second, h.Timeout)
+	assert.True(t, h.Has(hooks.PreScan))
+	assert.False(t, h.Has(hooks.OnError))
+
+	h, err = hooks.Parse(cleardir.Config{})
+	require.NoError(t, err)
+	assert.Empty(t, h.Commands)
+	assert.Equal(t, hooks.DefaultTimeout, h.Timeout)
+}
+
+func TestParseErr(t *testing.T) {
+	tests := []map[string]string{
+		{"post-scan": "true"},
+		{"timeout": "soon"},
+		{"timeout": "-1s"},
+	}
+	for _, values := range tests {
+		cfg := cleardir.Config{Sections: []cleardir.Section{{Kind: hooks.Section, Values: values}}}
+		_, err := hooks.Parse(cfg)
+		assert.Error(t, err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	script := writeScript(t, `
+echo "$CLEARDIR_HOOK root=$CLEARDIR_ROOT job=$CLEARDIR_JOB count=$CLEARDIR_COUNT bytes=$CLEARDIR_BYTES err=$CLEARDIR_ERROR"
+echo "$CLEARDIR_ROOTS"
+cat >&2
+`)
+	var out bytes.Buffer
+	h := hooks.Hooks{
+		Commands: map[string]string{hooks.OnError: script},
+		Output:   &out,
+	}
+	env := hooks.Env{
+		Roots: []string{"/a", "/b"},
+		Job:   "nightly",
+		Count: 3,
+		Bytes: 42,
+		Err:   errors.New("oops"),
+	}
+	err := h.Run(context.Background(), hooks.OnError, env, []byte("{\"plan\":1}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "on-error root=/a job=nightly count=3 bytes=42 err=oops\n/a\n/b\n{\"plan\":1}\n", out.String())
+
+	// Unconfigured hooks do nothing.
+	assert.NoError(t, h.Run(context.Background(), hooks.PreScan, env, nil))
+}
+
+func TestRunFail(t *testing.T) {
+	script := writeScript(t, "exit 3\n")
+	h := hooks.Hooks{Commands: map[string]string{hooks.PreRemove: script}}
+	err := h.Run(context.Background(), hooks.PreRemove, hooks.Env{}, nil)
+
+	var he *hooks.Error
+	require.ErrorAs(t, err, &he)
+	assert.Equal(t, hooks.PreRemove, he.Hook)
+	var ee *exec.ExitError
+	require.ErrorAs(t, err, &ee)
+	assert.Equal(t, 3, ee.ExitCode())
+}
+
+func TestRunTimeout(t *testing.T) {
+	// The background process keeps the output open unless it is killed too.
+	script := writeScript(t, "sleep 10 &\nsleep 10\n")
+	var out bytes.Buffer
+	h := hooks.Hooks{
+		Commands: map[string]string{hooks.PreScan: script},
+		Timeout:  20 * time.Millisecond,
+		Output:   &out,
+	}
+
+	start := time.Now()
+	err := h.Run(context.Background(), hooks.PreScan, hooks.Env{}, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "pre-scan hook failed: timed out after 20ms")
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestRunCanceled(t *testing.T) {
+	script := writeScript(t, "sleep 10\n")
+	h := hooks.Hooks{Commands: map[string]string{hooks.PostRemove: script}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := h.Run(ctx, hooks.PostRemove, hooks.Env{}, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package hooks
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// shellCommand runs line via sh, in a process group of its own.
+func shellCommand(line string) *exec.Cmd {
+	c := exec.Command("/bin/sh", "-c", line)
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return c
+}
+
+// killCommand kills c along with all processes of its process group.
+func killCommand(c *exec.Cmd) error {
+	return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+}
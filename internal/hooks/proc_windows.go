@@ -0,0 +1,15 @@
+package hooks
+
+import (
+	"os/exec"
+)
+
+// shellCommand runs line via cmd.exe.
+func shellCommand(line string) *exec.Cmd {
+	return exec.Command("cmd.exe", "/C", line)
+}
+
+// killCommand kills c.
+func killCommand(c *exec.Cmd) error {
+	return c.Process.Kill()
+}
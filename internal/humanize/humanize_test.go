@@ -0,0 +1,54 @@
+package humanize_test
+
+import (
+	"testing"
+
+	"github.com/echocrow/cleardir/internal/humanize"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 40, "3.0 TiB"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, humanize.Bytes(tc.n))
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"512B", 512},
+		{"2k", 2048},
+		{"2 KiB", 2048},
+		{"2kB", 2000},
+		{"1.5 GiB", 3 << 29},
+		{" 10MB ", 10 * 1000 * 1000},
+		{"1T", 1 << 40},
+	}
+	for _, tc := range tests {
+		got, err := humanize.ParseBytes(tc.in)
+		assert.NoError(t, err, tc.in)
+		assert.Equal(t, tc.want, got, tc.in)
+	}
+}
+
+func TestParseBytesErr(t *testing.T) {
+	for _, in := range []string{"", "MiB", "1 XB", "1.2.3", "-1"} {
+		_, err := humanize.ParseBytes(in)
+		assert.Error(t, err, in)
+	}
+}
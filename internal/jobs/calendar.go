@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// Calendar describes s for systemd timers, either as an interval for "@every"
+// schedules, or as calendar events in the format of systemd.time(7).
+//
+// Cron schedules that restrict both the day of month and the day of week are
+// described as two events, as systemd otherwise only matches days matching
+// both.
+func Calendar(s Schedule) (events []string, interval time.Duration) {
+	switch s := s.(type) {
+	case every:
+		return nil, time.Duration(s)
+	case cronSchedule:
+		return s.calendar(), 0
+	}
+	return nil, 0
+}
+
+func (c cronSchedule) calendar() []string {
+	clock := calendarList(c.hour, 0, 23, nil) + ":" + calendarList(c.minute, 0, 59, nil) + ":00"
+	month := calendarList(c.month, 1, 12, nil)
+	dom := calendarList(c.dom, 1, 31, nil)
+	dow := calendarList(c.dow, 0, 6, weekdays)
+
+	date := func(dom string) string {
+		return "*-" + month + "-" + dom + " " + clock
+	}
+	domAll := c.dom&domBits == domBits
+	dowAll := c.dow&dowBits == dowBits
+	switch {
+	case dowAll:
+		return []string{date(dom)}
+	case domAll:
+		return []string{dow + " " + date("*")}
+	default:
+		return []string{dow + " " + date("*"), date(dom)}
+	}
+}
+
+// calendarList formats the values in bits between min and max as "*", or as
+// a comma-separated list of values and ranges. Values are formatted via names
+// if given, or as two digits otherwise.
+func calendarList(bits uint64, min, max int, names []string) string {
+	all := true
+	for v := min; v <= max; v++ {
+		all = all && bits&(1<<uint(v)) != 0
+	}
+	if all {
+		return "*"
+	}
+
+	format := func(v int) string {
+		if names != nil {
+			return names[v]
+		}
+		if v < 10 {
+			return "0" + strconv.Itoa(v)
+		}
+		return strconv.Itoa(v)
+	}
+	parts := []string{}
+	for v := min; v <= max; v++ {
+		if bits&(1<<uint(v)) == 0 {
+			continue
+		}
+		end := v
+		for end+1 <= max && bits&(1<<uint(end+1)) != 0 {
+			end++
+		}
+		switch {
+		case end-v >= 2:
+			parts = append(parts, format(v)+".."+format(end))
+		case end > v:
+			parts = append(parts, format(v), format(end))
+		default:
+			parts = append(parts, format(v))
+		}
+		v = end
+	}
+	return strings.Join(parts, ",")
+}
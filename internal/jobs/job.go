@@ -0,0 +1,192 @@
+// Package jobs defines scheduled clearing jobs and runs them on schedule.
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/echocrow/cleardir/internal/humanize"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+)
+
+// Section is the config section kind of jobs, as in "[job NAME]".
+const Section = "job"
+
+const limitsSection = "limits"
+
+// Removal modes of a job.
+const (
+	// ModeDelete removes all clearable entries.
+	ModeDelete = "delete"
+	// ModeDry only reports clearable entries.
+	ModeDry = "dry"
+)
+
+// Job is a named clearing run of one or more roots.
+type Job struct {
+	Name string
+	// Roots lists the absolute paths of all directories to clear.
+	Roots []string
+	// Trivials lists file names that are safe for deletion, in addition to
+	// those of the config.
+	Trivials []string
+	// Schedule determines when the job runs as part of a daemon.
+	Schedule Schedule
+	// RawSchedule is the schedule as configured.
+	RawSchedule string
+	// Mode is the removal mode, either ModeDelete or ModeDry.
+	Mode string
+	// MaxDepth limits how many sub-directories to descend to at most, or
+	// -1 for no limit.
+	MaxDepth int
+	// MaxDelete refuses to clear more than this many items, or 0 for no
+	// limit.
+	MaxDelete int
+	// MaxDeleteSize refuses to clear files larger than this in total, or 0
+	// for no limit.
+	MaxDeleteSize int64
+	// SnapshotReport is the absolute path of a snapshot report to write on
+	// each removal, if any.
+	SnapshotReport string
+}
+
+// Parse reads all jobs from the "[job NAME]" sections of cfg.
+//
+// Jobs support the keys "roots" and "files" as comma-separated lists, and
+// "schedule", "mode", "max-depth", "max-delete", "max-delete-size", and
+// "snapshot-report". Limits default to those of the "[limits]" section.
+func Parse(cfg cleardir.Config) ([]Job, error) {
+	defaults := Job{Mode: ModeDelete, MaxDepth: -1}
+	if sec, ok := cfg.Section(limitsSection); ok {
+		if err := defaults.setLimits(sec.Values); err != nil {
+			return nil, fmt.Errorf("limits: %w", err)
+		}
+	}
+
+	jobs := []Job{}
+	seen := map[string]bool{}
+	for _, sec := range cfg.Sections {
+		if sec.Kind != Section {
+			continue
+		}
+		if sec.Name == "" {
+			return nil, errors.New("job section without a name")
+		}
+		if seen[sec.Name] {
+			return nil, fmt.Errorf("job %q: duplicate job", sec.Name)
+		}
+		seen[sec.Name] = true
+
+		j, err := parseJob(defaults, sec)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", sec.Name, err)
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+var jobKeys = map[string]bool{
+	"roots":           true,
+	"files":           true,
+	"schedule":        true,
+	"mode":            true,
+	"max-depth":       true,
+	"max-delete":      true,
+	"max-delete-size": true,
+	"snapshot-report": true,
+}
+
+func parseJob(j Job, sec cleardir.Section) (Job, error) {
+	j.Name = sec.Name
+	for k := range sec.Values {
+		if !jobKeys[k] {
+			return j, fmt.Errorf("unknown key %q", k)
+		}
+	}
+
+	j.Roots = splitList(sec.Values["roots"])
+	if len(j.Roots) == 0 {
+		return j, errors.New("no roots")
+	}
+	for _, r := range j.Roots {
+		if !filepath.IsAbs(r) {
+			return j, fmt.Errorf("root %q is not absolute", r)
+		}
+	}
+	j.Trivials = splitList(sec.Values["files"])
+
+	j.RawSchedule = sec.Values["schedule"]
+	if j.RawSchedule != "" {
+		s, err := ParseSchedule(j.RawSchedule)
+		if err != nil {
+			return j, err
+		}
+		j.Schedule = s
+	}
+
+	if m, ok := sec.Values["mode"]; ok {
+		if m != ModeDelete && m != ModeDry {
+			return j, fmt.Errorf("invalid mode %q", m)
+		}
+		j.Mode = m
+	}
+
+	if v, ok := sec.Values["max-depth"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return j, fmt.Errorf("invalid max-depth %q", v)
+		}
+		j.MaxDepth = n
+	}
+	if err := j.setLimits(sec.Values); err != nil {
+		return j, err
+	}
+
+	j.SnapshotReport = sec.Values["snapshot-report"]
+	if j.SnapshotReport != "" && !filepath.IsAbs(j.SnapshotReport) {
+		return j, fmt.Errorf("snapshot-report %q is not absolute", j.SnapshotReport)
+	}
+	return j, nil
+}
+
+func (j *Job) setLimits(values map[string]string) error {
+	if v, ok := values["max-delete"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid max-delete %q", v)
+		}
+		j.MaxDelete = n
+	}
+	if v, ok := values["max-delete-size"]; ok {
+		n, err := humanize.ParseBytes(v)
+		if err != nil {
+			return fmt.Errorf("invalid max-delete-size: %w", err)
+		}
+		j.MaxDeleteSize = n
+	}
+	return nil
+}
+
+// Find returns the job of the given name.
+func Find(jobs []Job, name string) (Job, bool) {
+	for _, j := range jobs {
+		if j.Name == name {
+			return j, true
+		}
+	}
+	return Job{}, false
+}
+
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,102 @@
+package jobs_test
+
+import (
+	"path"
+	"testing"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/echocrow/cleardir/internal/jobs"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/echocrow/osa/testos"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readConfig(t *testing.T, contents string) cleardir.Config {
+	os, reset := vos.Patch()
+	defer reset()
+	cfgPath := path.Join(vos.MkTempDir(os), "cfg")
+	testos.RequireWrite(t, os, cfgPath, cleardir.ConfigHeader+"\n"+contents)
+	cfg, _, err := cleardir.ReadConfig(cfgPath)
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestParse(t *testing.T) {
+	cfg := readConfig(t, heredoc.Doc(`
+		.DS_Store
+
+		[limits]
+		max-delete = 100
+
+		[job downloads]
+		roots = /home/me/Downloads, /srv/tmp
+		files = Thumbs.db
+		schedule = @every 1h
+		mode = dry
+		max-depth = 3
+		max-delete-size = 1 GiB
+
+		[job manual]
+		roots = /srv/other
+		max-delete = 0
+		snapshot-report = /var/log/cleardir/manual.txt
+	`))
+
+	got, err := jobs.Parse(cfg)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+
+	dl := got[0]
+	assert.Equal(t, "downloads", dl.Name)
+	assert.Equal(t, []string{"/home/me/Downloads", "/srv/tmp"}, dl.Roots)
+	assert.Equal(t, []string{"Thumbs.db"}, dl.Trivials)
+	assert.Equal(t, "@every 1h", dl.RawSchedule)
+	assert.NotNil(t, dl.Schedule)
+	assert.Equal(t, jobs.ModeDry, dl.Mode)
+	assert.Equal(t, 3, dl.MaxDepth)
+	assert.Equal(t, 100, dl.MaxDelete)
+	assert.Equal(t, int64(1<<30), dl.MaxDeleteSize)
+
+	manual := got[1]
+	assert.Equal(t, "manual", manual.Name)
+	assert.Equal(t, []string{}, manual.Trivials)
+	assert.Nil(t, manual.Schedule)
+	assert.Equal(t, jobs.ModeDelete, manual.Mode)
+	assert.Equal(t, -1, manual.MaxDepth)
+	assert.Equal(t, 0, manual.MaxDelete)
+	assert.Equal(t, "/var/log/cleardir/manual.txt", manual.SnapshotReport)
+
+	j, ok := jobs.Find(got, "manual")
+	assert.True(t, ok)
+	assert.Equal(t, manual, j)
+	_, ok = jobs.Find(got, "missing")
+	assert.False(t, ok)
+}
+
+func TestParseErr(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{"No Name", "[job]\nroots = /a"},
+		{"Duplicate", "[job a]\nroots = /a\n[job a]\nroots = /b"},
+		{"No Roots", "[job a]\nschedule = @daily"},
+		{"Relative Root", "[job a]\nroots = some/path"},
+		{"Unknown Key", "[job a]\nroots = /a\nroot = /b"},
+		{"Bad Schedule", "[job a]\nroots = /a\nschedule = @sometimes"},
+		{"Bad Mode", "[job a]\nroots = /a\nmode = shred"},
+		{"Bad Max Depth", "[job a]\nroots = /a\nmax-depth = deep"},
+		{"Bad Max Delete", "[job a]\nroots = /a\nmax-delete = -1"},
+		{"Bad Max Delete Size", "[job a]\nroots = /a\nmax-delete-size = huge"},
+		{"Relative Snapshot Report", "[job a]\nroots = /a\nsnapshot-report = report.txt"},
+		{"Bad Default Limit", "[limits]\nmax-delete = many\n[job a]\nroots = /a"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := jobs.Parse(readConfig(t, tc.contents))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package jobs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/echocrow/cleardir/internal/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	// A Wednesday.
+	start := time.Date(2021, 3, 10, 12, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"@every 90m", []string{"2021-03-10 14:00:45", "2021-03-10 15:30:45"}},
+		{"@hourly", []string{"2021-03-10 13:00:00", "2021-03-10 14:00:00"}},
+		{"@daily", []string{"2021-03-11 00:00:00", "2021-03-12 00:00:00"}},
+		{"@weekly", []string{"2021-03-14 00:00:00", "2021-03-21 00:00:00"}},
+		{"@monthly", []string{"2021-04-01 00:00:00", "2021-05-01 00:00:00"}},
+		{"*/20 * * * *", []string{"2021-03-10 12:40:00", "2021-03-10 13:00:00"}},
+		{"15,45 3 * * *", []string{"2021-03-11 03:15:00", "2021-03-11 03:45:00"}},
+		{"0 9-17/4 * * *", []string{"2021-03-10 13:00:00", "2021-03-10 17:00:00", "2021-03-11 09:00:00"}},
+		{"0 0 * * 7", []string{"2021-03-14 00:00:00", "2021-03-21 00:00:00"}},
+		{"0 0 * * 1-5", []string{"2021-03-11 00:00:00", "2021-03-12 00:00:00", "2021-03-15 00:00:00"}},
+		{"0 0 13 * 5", []string{"2021-03-12 00:00:00", "2021-03-13 00:00:00", "2021-03-19 00:00:00"}},
+		{"0 0 31 * *", []string{"2021-03-31 00:00:00", "2021-05-31 00:00:00"}},
+		{"0 0 29 2 *", []string{"2024-02-29 00:00:00"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.spec, func(t *testing.T) {
+			s, err := jobs.ParseSchedule(tc.spec)
+			require.NoError(t, err)
+			got := []string{}
+			for now := start; len(got) < len(tc.want); {
+				now = s.Next(now)
+				got = append(got, now.Format("2006-01-02 15:04:05"))
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseScheduleErr(t *testing.T) {
+	tests := []string{
+		"",
+		"@every",
+		"@every -1h",
+		"@sometimes",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	}
+	for _, spec := range tests {
+		t.Run(spec, func(t *testing.T) {
+			_, err := jobs.ParseSchedule(spec)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	tests := []struct {
+		spec         string
+		wantEvents   []string
+		wantInterval time.Duration
+	}{
+		{"@every 90m", nil, 90 * time.Minute},
+		{"@hourly", []string{"*-*-* *:00:00"}, 0},
+		{"@weekly", []string{"Sun *-*-* 00:00:00"}, 0},
+		{"@yearly", []string{"*-01-01 00:00:00"}, 0},
+		{"*/20 3,4 * * *", []string{"*-*-* 03,04:00,20,40:00"}, 0},
+		{"30 9-17 * 1-6 1-5", []string{"Mon..Fri *-01..06-* 09..17:30:00"}, 0},
+		{"0 0 * * 0,7", []string{"Sun *-*-* 00:00:00"}, 0},
+		{"0 0 13 * 5", []string{"Fri *-*-* 00:00:00", "*-*-13 00:00:00"}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.spec, func(t *testing.T) {
+			s, err := jobs.ParseSchedule(tc.spec)
+			require.NoError(t, err)
+			events, interval := jobs.Calendar(s)
+			assert.Equal(t, tc.wantEvents, events)
+			assert.Equal(t, tc.wantInterval, interval)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Scheduler runs jobs on their schedules.
+type Scheduler struct {
+	// Jobs lists all jobs to run. Jobs without a schedule are ignored.
+	Jobs []Job
+	// Clock tells the time, or defaults to the system clock if nil.
+	Clock Clock
+	// Run runs a single job. Runs of different jobs may be concurrent, but
+	// each job only runs once at a time.
+	Run func(ctx context.Context, j Job)
+	// OnSkip is called when a job is due while its previous run is still in
+	// progress. The due run is then skipped.
+	OnSkip func(j Job)
+}
+
+// Start runs all jobs on schedule until ctx is canceled, then waits for any
+// running jobs to return, and returns the context error.
+func (s *Scheduler) Start(ctx context.Context) error {
+	clock := s.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	running := make([]int32, len(s.Jobs))
+
+	now := clock.Now()
+	next := make([]time.Time, len(s.Jobs))
+	for i, j := range s.Jobs {
+		if j.Schedule != nil {
+			next[i] = j.Schedule.Next(now)
+		}
+	}
+
+	for {
+		var due time.Time
+		for _, t := range next {
+			if !t.IsZero() && (due.IsZero() || t.Before(due)) {
+				due = t
+			}
+		}
+		var wake <-chan time.Time
+		if !due.IsZero() {
+			wake = clock.After(due.Sub(now))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+
+		now = clock.Now()
+		for i, j := range s.Jobs {
+			if next[i].IsZero() || now.Before(next[i]) {
+				continue
+			}
+			next[i] = j.Schedule.Next(now)
+			if !atomic.CompareAndSwapInt32(&running[i], 0, 1) {
+				if s.OnSkip != nil {
+					s.OnSkip(j)
+				}
+				continue
+			}
+			wg.Add(1)
+			go func(i int, j Job) {
+				defer wg.Done()
+				defer atomic.StoreInt32(&running[i], 0)
+				s.Run(ctx, j)
+			}(i, j)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package jobs_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/echocrow/cleardir/internal/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startScheduler starts s in the background, and returns a function that
+// stops it and returns its error.
+func startScheduler(s *jobs.Scheduler) func() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- s.Start(ctx) }()
+	return func() error {
+		cancel()
+		return <-errc
+	}
+}
+
+func mustSchedule(t *testing.T, spec string) jobs.Schedule {
+	s, err := jobs.ParseSchedule(spec)
+	require.NoError(t, err)
+	return s
+}
+
+func TestScheduler(t *testing.T) {
+	clock := newFakeClock(time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC))
+	runs := make(chan string, 10)
+	s := &jobs.Scheduler{
+		Jobs: []jobs.Job{
+			{Name: "often", Schedule: mustSchedule(t, "@every 10m")},
+			{Name: "hourly", Schedule: mustSchedule(t, "@hourly")},
+			{Name: "manual"},
+		},
+		Clock: clock,
+		Run: func(ctx context.Context, j jobs.Job) {
+			runs <- clock.Now().Format("15:04") + " " + j.Name
+		},
+	}
+	stop := startScheduler(s)
+
+	got := []string{}
+	for _, n := range []int{1, 1, 1, 1, 1, 2} {
+		clock.BlockUntil(1)
+		clock.Advance(10 * time.Minute)
+		for i := 0; i < n; i++ {
+			got = append(got, <-runs)
+		}
+	}
+	assert.ErrorIs(t, stop(), context.Canceled)
+	assert.Empty(t, runs)
+
+	sort.Strings(got)
+	assert.Equal(t, []string{
+		"12:10 often",
+		"12:20 often",
+		"12:30 often",
+		"12:40 often",
+		"12:50 often",
+		"13:00 hourly",
+		"13:00 often",
+	}, got)
+}
+
+func TestSchedulerSkipsOverlappingRuns(t *testing.T) {
+	clock := newFakeClock(time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC))
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	skipped := make(chan string, 10)
+	var mu sync.Mutex
+	runs := 0
+	s := &jobs.Scheduler{
+		Jobs:  []jobs.Job{{Name: "slow", Schedule: mustSchedule(t, "@every 1m")}},
+		Clock: clock,
+		Run: func(ctx context.Context, j jobs.Job) {
+			mu.Lock()
+			runs++
+			mu.Unlock()
+			started <- struct{}{}
+			<-release
+		},
+		OnSkip: func(j jobs.Job) { skipped <- j.Name },
+	}
+	stop := startScheduler(s)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-started
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	assert.Equal(t, "slow", <-skipped)
+
+	// The previous run may take a moment to be marked as done.
+	close(release)
+	for restarted := false; !restarted; {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		select {
+		case <-started:
+			restarted = true
+		case <-skipped:
+		}
+	}
+
+	assert.ErrorIs(t, stop(), context.Canceled)
+	assert.Equal(t, 2, runs)
+}
+
+func TestSchedulerWaitsForRuns(t *testing.T) {
+	clock := newFakeClock(time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC))
+	started := make(chan struct{})
+	done := false
+	s := &jobs.Scheduler{
+		Jobs:  []jobs.Job{{Name: "slow", Schedule: mustSchedule(t, "@every 1m")}},
+		Clock: clock,
+		Run: func(ctx context.Context, j jobs.Job) {
+			close(started)
+			<-ctx.Done()
+			done = true
+		},
+	}
+	stop := startScheduler(s)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-started
+
+	assert.ErrorIs(t, stop(), context.Canceled)
+	assert.True(t, done)
+}
@@ -0,0 +1,33 @@
+// Package ostest provides OS abstractions that simulate failures in tests.
+package ostest
+
+import (
+	"errors"
+
+	"github.com/echocrow/osa"
+	"github.com/scylladb/go-set/strset"
+)
+
+// ErrUnreadable is the error of reading directories made unreadable via
+// PatchUnreadable.
+var ErrUnreadable = errors.New("unreadable")
+
+// unreadableOS wraps an OS abstraction and fails to read select directories.
+type unreadableOS struct {
+	osa.I
+	dirs *strset.Set
+}
+
+func (o unreadableOS) ReadDir(name string) ([]osa.DirEntry, error) {
+	if o.dirs.Has(name) {
+		return nil, &osa.PathError{Op: "readdir", Path: name, Err: ErrUnreadable}
+	}
+	return o.I.ReadDir(name)
+}
+
+// PatchUnreadable patches the current OS abstraction with o, failing to read
+// dirs with ErrUnreadable, and returns a function that restores the previous
+// one.
+func PatchUnreadable(o osa.I, dirs ...string) func() {
+	return osa.Patch(unreadableOS{o, strset.New(dirs...)})
+}
@@ -0,0 +1,163 @@
+// Package systemd generates systemd units that run cleardir jobs.
+package systemd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/echocrow/cleardir/internal/jobs"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+)
+
+// Options configures generated units.
+type Options struct {
+	// Exec is the absolute path of the cleardir executable.
+	Exec string
+	// Config is the absolute path of the configuration file defining the job.
+	Config string
+	// User generates units for the per-user service manager.
+	User bool
+}
+
+// UnitName returns the name of the units of job j, without a suffix.
+func UnitName(j jobs.Job) string {
+	return "cleardir-" + escapeName(j.Name)
+}
+
+// Service generates a oneshot service unit running job j once.
+//
+// The service is sandboxed, and may only write to the roots of the job and
+// the directory of its snapshot report.
+func Service(j jobs.Job, opts Options) (string, error) {
+	if !filepath.IsAbs(opts.Exec) {
+		return "", fmt.Errorf("executable path %q is not absolute", opts.Exec)
+	}
+	if !filepath.IsAbs(opts.Config) {
+		return "", fmt.Errorf("config path %q is not absolute", opts.Config)
+	}
+
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=Clear empty directories of cleardir job %s\n", escapeSpecifiers(j.Name))
+	b.WriteString("Documentation=https://github.com/echocrow/cleardir\n")
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=oneshot\n")
+	fmt.Fprintf(&b, "ExecStart=%s\n", execLine(opts.Exec, "jobs", "run", "--config", opts.Config, "--", j.Name))
+
+	b.WriteString("NoNewPrivileges=yes\n")
+	b.WriteString("ProtectSystem=strict\n")
+	b.WriteString("ProtectHome=read-only\n")
+	paths := make([]string, 0, len(j.Roots)+1)
+	for _, r := range j.Roots {
+		paths = append(paths, quoteArg(r))
+	}
+	// Snapshot reports are rewritten on each run.
+	if j.SnapshotReport != "" {
+		paths = append(paths, quoteArg(filepath.Dir(j.SnapshotReport)))
+	}
+	fmt.Fprintf(&b, "ReadWritePaths=%s\n", strings.Join(paths, " "))
+	// Run locks are kept in the runtime directory, and shared with other runs.
+	b.WriteString("RuntimeDirectory=cleardir\n")
+	b.WriteString("RuntimeDirectoryPreserve=yes\n")
+	// A private /tmp would hide any roots inside it.
+	if !anyNested([]string{"/tmp", "/var/tmp"}, j.Roots) {
+		b.WriteString("PrivateTmp=yes\n")
+	}
+	if !opts.User {
+		b.WriteString("PrivateDevices=yes\n")
+		b.WriteString("ProtectKernelTunables=yes\n")
+		b.WriteString("ProtectKernelModules=yes\n")
+		b.WriteString("ProtectControlGroups=yes\n")
+		b.WriteString("RestrictSUIDSGID=yes\n")
+		b.WriteString("LockPersonality=yes\n")
+	}
+	return b.String(), nil
+}
+
+// Timer generates a timer unit activating the service of job j on the job's
+// schedule.
+func Timer(j jobs.Job) (string, error) {
+	if j.Schedule == nil {
+		return "", errors.New("job has no schedule")
+	}
+	events, interval := jobs.Calendar(j.Schedule)
+
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=Run cleardir job %s on schedule\n", escapeSpecifiers(j.Name))
+	b.WriteString("Documentation=https://github.com/echocrow/cleardir\n")
+	b.WriteString("\n[Timer]\n")
+	fmt.Fprintf(&b, "# Schedule: %s\n", escapeSpecifiers(j.RawSchedule))
+	if interval > 0 {
+		fmt.Fprintf(&b, "OnActiveSec=%s\n", interval)
+		fmt.Fprintf(&b, "OnUnitActiveSec=%s\n", interval)
+	} else {
+		for _, e := range events {
+			fmt.Fprintf(&b, "OnCalendar=%s\n", e)
+		}
+		b.WriteString("Persistent=yes\n")
+	}
+	fmt.Fprintf(&b, "Unit=%s.service\n", UnitName(j))
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=timers.target\n")
+	return b.String(), nil
+}
+
+// escapeName escapes s for use in unit names like systemd-escape(1), but
+// keeps dashes for readability.
+func escapeName(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '/':
+			b.WriteByte('-')
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == ':', c == '_', c == '-', c == '.' && i > 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		}
+	}
+	return b.String()
+}
+
+// escapeSpecifiers escapes "%" specifiers in unit file values.
+func escapeSpecifiers(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// execLine formats a command line for ExecStart.
+func execLine(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = strings.ReplaceAll(quoteArg(a), "$", "$$")
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteArg quotes a as a single word of a unit file value if necessary, and
+// escapes any specifiers.
+func quoteArg(a string) string {
+	a = escapeSpecifiers(a)
+	if a != "" && !strings.ContainsAny(a, " \t\"'\\;") {
+		return a
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(a) + `"`
+}
+
+// anyNested reports whether any of paths equals or is nested inside any of
+// dirs.
+func anyNested(dirs, paths []string) bool {
+	for _, d := range dirs {
+		for _, p := range paths {
+			if p == d || cleardir.IsNested(d, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package systemd_test
+
+import (
+	"testing"
+
+	"github.com/echocrow/cleardir/internal/jobs"
+	"github.com/echocrow/cleardir/internal/systemd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"downloads", "cleardir-downloads"},
+		{"srv-tmp_2", "cleardir-srv-tmp_2"},
+		{"my files", `cleardir-my\x20files`},
+		{"a/b", "cleardir-a-b"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, systemd.UnitName(jobs.Job{Name: tc.name}))
+	}
+}
+
+func TestService(t *testing.T) {
+	j := jobs.Job{Name: "$HOME 50%", Roots: []string{"/var/tmp/x", `/srv/"quoted"`}}
+	got, err := systemd.Service(j, systemd.Options{Exec: "/bin/cleardir", Config: "/etc/cfg", User: true})
+	require.NoError(t, err)
+	assert.Contains(t, got, `ExecStart=/bin/cleardir jobs run --config /etc/cfg -- "$$HOME 50%%"`+"\n")
+	assert.Contains(t, got, `ReadWritePaths=/var/tmp/x "/srv/\"quoted\""`+"\n")
+	assert.NotContains(t, got, "PrivateTmp")
+	assert.NotContains(t, got, "PrivateDevices")
+
+	j.SnapshotReport = "/var/log/cleardir/report.txt"
+	got, err = systemd.Service(j, systemd.Options{Exec: "/bin/cleardir", Config: "/etc/cfg"})
+	require.NoError(t, err)
+	assert.Contains(t, got, `ReadWritePaths=/var/tmp/x "/srv/\"quoted\"" /var/log/cleardir`+"\n")
+
+	_, err = systemd.Service(j, systemd.Options{Exec: "cleardir", Config: "/etc/cfg"})
+	assert.Error(t, err)
+	_, err = systemd.Service(j, systemd.Options{Exec: "/bin/cleardir", Config: "cfg"})
+	assert.Error(t, err)
+}
+
+func TestTimerErr(t *testing.T) {
+	_, err := systemd.Timer(jobs.Job{Name: "manual"})
+	assert.Error(t, err)
+}
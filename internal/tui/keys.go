@@ -0,0 +1,86 @@
+package tui
+
+// Key is a single key press. Printable keys are represented by their
+// character, all others by one of the Key constants.
+type Key string
+
+// Special keys.
+const (
+	KeyUp     Key = "up"
+	KeyDown   Key = "down"
+	KeyLeft   Key = "left"
+	KeyRight  Key = "right"
+	KeyPgUp   Key = "pgup"
+	KeyPgDown Key = "pgdown"
+	KeyHome   Key = "home"
+	KeyEnd    Key = "end"
+	KeyEnter  Key = "enter"
+	KeySpace  Key = "space"
+	KeyEsc    Key = "esc"
+	KeyCtrlC  Key = "ctrl+c"
+)
+
+var escKeys = map[string]Key{
+	"[A":  KeyUp,
+	"[B":  KeyDown,
+	"[C":  KeyRight,
+	"[D":  KeyLeft,
+	"[H":  KeyHome,
+	"[F":  KeyEnd,
+	"[1~": KeyHome,
+	"[4~": KeyEnd,
+	"[5~": KeyPgUp,
+	"[6~": KeyPgDown,
+	"OA":  KeyUp,
+	"OB":  KeyDown,
+	"OC":  KeyRight,
+	"OD":  KeyLeft,
+	"OH":  KeyHome,
+	"OF":  KeyEnd,
+}
+
+// ParseKeys parses raw terminal input into key presses. Unknown escape
+// sequences are dropped.
+func ParseKeys(b []byte) []Key {
+	keys := []Key{}
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == 0x1b:
+			seq, n := escSeq(b[i+1:])
+			if n == 0 {
+				keys = append(keys, KeyEsc)
+				continue
+			}
+			if k, ok := escKeys[seq]; ok {
+				keys = append(keys, k)
+			}
+			i += n
+		case c == '\r' || c == '\n':
+			keys = append(keys, KeyEnter)
+		case c == ' ':
+			keys = append(keys, KeySpace)
+		case c == 0x03:
+			keys = append(keys, KeyCtrlC)
+		case c >= 0x20 && c < 0x7f:
+			keys = append(keys, Key(c))
+		}
+	}
+	return keys
+}
+
+// escSeq returns the CSI or SS3 sequence at the start of b, without the
+// leading escape character, along with its length.
+func escSeq(b []byte) (string, int) {
+	if len(b) < 2 || (b[0] != '[' && b[0] != 'O') {
+		return "", 0
+	}
+	if b[0] == 'O' {
+		return string(b[:2]), 2
+	}
+	for i := 1; i < len(b); i++ {
+		if c := b[i]; c >= 0x40 && c <= 0x7e {
+			return string(b[:i+1]), i + 1
+		}
+	}
+	return "", 0
+}
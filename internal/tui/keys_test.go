@@ -0,0 +1,31 @@
+package tui_test
+
+import (
+	"testing"
+
+	"github.com/echocrow/cleardir/internal/tui"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []tui.Key
+	}{
+		{"Empty", "", []tui.Key{}},
+		{"Chars", "jkq", []tui.Key{"j", "k", "q"}},
+		{"Special", " \r\x03", []tui.Key{tui.KeySpace, tui.KeyEnter, tui.KeyCtrlC}},
+		{"Arrows", "\x1b[A\x1b[B\x1b[C\x1b[D", []tui.Key{tui.KeyUp, tui.KeyDown, tui.KeyRight, tui.KeyLeft}},
+		{"SS3 Arrows", "\x1bOA\x1bOB", []tui.Key{tui.KeyUp, tui.KeyDown}},
+		{"Pages", "\x1b[5~\x1b[6~", []tui.Key{tui.KeyPgUp, tui.KeyPgDown}},
+		{"Esc", "\x1b", []tui.Key{tui.KeyEsc}},
+		{"Unknown Sequence", "\x1b[99~j", []tui.Key{"j"}},
+		{"Non-Printable", "\x01a", []tui.Key{"a"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tui.ParseKeys([]byte(tc.in)))
+		})
+	}
+}
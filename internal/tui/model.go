@@ -0,0 +1,487 @@
+// Package tui implements a full-screen terminal UI to browse and select
+// clearable entries.
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/echocrow/cleardir/internal/humanize"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+)
+
+// Root lists all clearable matches of a single scan root.
+type Root struct {
+	Path    string
+	Matches []cleardir.Match
+}
+
+// Action is the outcome of a TUI session.
+type Action int
+
+const (
+	// ActionNone denotes a running session.
+	ActionNone Action = iota
+	// ActionQuit denotes a session that ended without clearing anything.
+	ActionQuit
+	// ActionDelete denotes a session that ended by confirming the deletion of
+	// all selected entries.
+	ActionDelete
+)
+
+const sizeWidth = 10
+
+// node is a single entry of the browsed tree.
+type node struct {
+	name     string
+	path     string
+	match    *cleardir.Match
+	parent   *node
+	children []*node
+	// byName indexes children by their names.
+	byName   map[string]*node
+	depth    int
+	selected bool
+	expanded bool
+	// size is the total size of all clearable files below and including the
+	// node.
+	size int64
+	// rules lists the rules of all clearable files below and including the
+	// node.
+	rules map[string]bool
+}
+
+func (n *node) isDir() bool {
+	return n.match == nil || n.match.Kind == cleardir.KindDir
+}
+
+// Model holds the state of a TUI session.
+//
+// The model is updated via Update and rendered via View, and never touches a
+// terminal itself.
+type Model struct {
+	roots      []*node
+	order      []*node
+	rows       []*node
+	cursor     int
+	offset     int
+	width      int
+	height     int
+	rules      []string
+	filter     string
+	confirming bool
+	action     Action
+}
+
+// New creates a model of all matches of roots, with all entries selected.
+//
+// Matches are expected in post-order, as sent by cleardir.Finder.
+func New(roots []Root, width, height int) *Model {
+	m := &Model{}
+	rules := map[string]bool{}
+	for _, r := range roots {
+		root := &node{
+			name:     r.Path,
+			path:     r.Path,
+			expanded: true,
+			rules:    map[string]bool{},
+		}
+		for i := range r.Matches {
+			match := &r.Matches[i]
+			n := root.child(match.Path)
+			n.match = match
+			n.selected = true
+			n.expanded = false
+			m.order = append(m.order, n)
+			if match.Rule != "" {
+				rules[match.Rule] = true
+				for a := n; a != nil; a = a.parent {
+					a.size += match.Size
+					a.rules[match.Rule] = true
+				}
+			}
+		}
+		root.sort()
+		m.roots = append(m.roots, root)
+	}
+	for r := range rules {
+		m.rules = append(m.rules, r)
+	}
+	sort.Strings(m.rules)
+	m.Resize(width, height)
+	return m
+}
+
+// child returns the node at path below n, creating any missing nodes.
+func (n *node) child(path string) *node {
+	rel, err := filepath.Rel(n.path, path)
+	if err != nil {
+		return n
+	}
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		c := n.byName[name]
+		if c == nil {
+			c = &node{
+				name:     name,
+				path:     filepath.Join(n.path, name),
+				parent:   n,
+				depth:    n.depth + 1,
+				expanded: true,
+				rules:    map[string]bool{},
+			}
+			n.children = append(n.children, c)
+			if n.byName == nil {
+				n.byName = map[string]*node{}
+			}
+			n.byName[name] = c
+		}
+		n = c
+	}
+	return n
+}
+
+func (n *node) sort() {
+	sort.Slice(n.children, func(i, j int) bool {
+		return n.children[i].name < n.children[j].name
+	})
+	for _, c := range n.children {
+		c.sort()
+	}
+}
+
+// Resize updates the dimensions of the terminal.
+func (m *Model) Resize(width, height int) {
+	m.width, m.height = width, height
+	m.refresh()
+}
+
+// Action reports the outcome of the session so far.
+func (m *Model) Action() Action {
+	return m.action
+}
+
+// Selected lists the paths of all selected matches, in their original order.
+func (m *Model) Selected() []string {
+	paths := []string{}
+	for _, n := range m.order {
+		if n.selected {
+			paths = append(paths, n.path)
+		}
+	}
+	return paths
+}
+
+// Update updates the model according to a single key press.
+func (m *Model) Update(k Key) {
+	if m.confirming {
+		m.confirming = false
+		if k == "y" {
+			m.action = ActionDelete
+		}
+		return
+	}
+
+	switch k {
+	case KeyUp, "k":
+		m.move(-1)
+	case KeyDown, "j":
+		m.move(1)
+	case KeyPgUp:
+		m.move(-m.pageSize())
+	case KeyPgDown:
+		m.move(m.pageSize())
+	case KeyHome, "g":
+		m.move(-len(m.rows))
+	case KeyEnd, "G":
+		m.move(len(m.rows))
+	case KeyRight, "l":
+		if n := m.current(); n != nil && len(n.children) > 0 {
+			n.expanded = true
+		}
+	case KeyLeft, "h":
+		if n := m.current(); n != nil {
+			if n.expanded && len(n.children) > 0 && n.parent != nil {
+				n.expanded = false
+			} else if n.parent != nil {
+				m.jumpTo(n.parent)
+			}
+		}
+	case KeyEnter:
+		if n := m.current(); n != nil && len(n.children) > 0 && n.parent != nil {
+			n.expanded = !n.expanded
+		}
+	case KeySpace:
+		if n := m.current(); n != nil {
+			m.toggle(n)
+		}
+	case "a":
+		all := true
+		for _, n := range m.order {
+			all = all && n.selected
+		}
+		for _, n := range m.order {
+			n.selected = !all
+		}
+	case "f":
+		m.cycleFilter()
+	case "d":
+		if len(m.Selected()) > 0 {
+			m.confirming = true
+		}
+	case "q", KeyEsc, KeyCtrlC:
+		m.action = ActionQuit
+	}
+	m.refresh()
+}
+
+func (m *Model) current() *node {
+	if m.cursor < 0 || m.cursor >= len(m.rows) {
+		return nil
+	}
+	return m.rows[m.cursor]
+}
+
+func (m *Model) move(delta int) {
+	m.cursor += delta
+	if m.cursor >= len(m.rows) {
+		m.cursor = len(m.rows) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+func (m *Model) jumpTo(n *node) {
+	m.refresh()
+	for i, r := range m.rows {
+		if r == n {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+// toggle flips the selection of n and all of its clearable descendants.
+//
+// Deselecting an entry also deselects its ancestors, as they are then no
+// longer empty. Selecting an entry selects any clearable ancestors whose
+// contents are then fully selected.
+func (m *Model) toggle(n *node) {
+	want := !n.selected
+	if n.match == nil {
+		want = !n.fullySelected()
+	}
+	n.setSelected(want)
+	for a := n.parent; a != nil && a.match != nil; a = a.parent {
+		a.selected = want && a.childrenSelected()
+		if !a.selected && want {
+			break
+		}
+	}
+}
+
+func (n *node) setSelected(sel bool) {
+	if n.match != nil {
+		n.selected = sel
+	}
+	for _, c := range n.children {
+		c.setSelected(sel)
+	}
+}
+
+func (n *node) childrenSelected() bool {
+	for _, c := range n.children {
+		if !c.selected {
+			return false
+		}
+	}
+	return true
+}
+
+// fullySelected reports whether all clearable entries below n are selected.
+func (n *node) fullySelected() bool {
+	for _, c := range n.children {
+		if c.match != nil && !c.selected || !c.fullySelected() {
+			return false
+		}
+	}
+	return true
+}
+
+// anySelected reports whether n or any entry below it is selected.
+func (n *node) anySelected() bool {
+	if n.selected {
+		return true
+	}
+	for _, c := range n.children {
+		if c.anySelected() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Model) cycleFilter() {
+	if len(m.rules) == 0 {
+		return
+	}
+	i := sort.SearchStrings(m.rules, m.filter)
+	switch {
+	case m.filter == "":
+		m.filter = m.rules[0]
+	case i+1 < len(m.rules):
+		m.filter = m.rules[i+1]
+	default:
+		m.filter = ""
+	}
+	m.cursor = 0
+}
+
+// visible reports whether n passes the current rule filter.
+func (m *Model) visible(n *node) bool {
+	if m.filter == "" || n.parent == nil {
+		return true
+	}
+	return n.rules[m.filter]
+}
+
+// refresh recomputes all visible rows, and keeps the cursor in view.
+func (m *Model) refresh() {
+	cur := m.current()
+	m.rows = m.rows[:0]
+	var walk func(n *node)
+	walk = func(n *node) {
+		if !m.visible(n) {
+			return
+		}
+		m.rows = append(m.rows, n)
+		if !n.expanded {
+			return
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	for _, r := range m.roots {
+		walk(r)
+	}
+
+	for i, r := range m.rows {
+		if r == cur {
+			m.cursor = i
+		}
+	}
+	m.move(0)
+
+	page := m.pageSize()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+page {
+		m.offset = m.cursor - page + 1
+	}
+	if m.offset < 0 {
+		m.offset = 0
+	}
+}
+
+// pageSize is the number of rows that fit between the header and footer.
+func (m *Model) pageSize() int {
+	if page := m.height - 2; page > 0 {
+		return page
+	}
+	return 1
+}
+
+// View renders the model as a full screen of text.
+func (m *Model) View() string {
+	var b strings.Builder
+
+	sel, size := 0, int64(0)
+	for _, n := range m.order {
+		if n.selected {
+			sel++
+			if n.match.Kind == cleardir.KindFile {
+				size += n.match.Size
+			}
+		}
+	}
+	filter := "all"
+	if m.filter != "" {
+		filter = m.filter
+	}
+	b.WriteString(m.fit(fmt.Sprintf("%d of %d selected (%s) | rule: %s",
+		sel, len(m.order), humanize.Bytes(size), filter)))
+	b.WriteString("\n")
+
+	end := m.offset + m.pageSize()
+	if end > len(m.rows) {
+		end = len(m.rows)
+	}
+	for i := m.offset; i < end; i++ {
+		b.WriteString(m.row(m.rows[i], i == m.cursor))
+		b.WriteString("\n")
+	}
+	for i := end - m.offset; i < m.pageSize(); i++ {
+		b.WriteString("\n")
+	}
+
+	if m.confirming {
+		b.WriteString(m.fit(fmt.Sprintf("Delete %d selected entries? [y/N]", sel)))
+	} else {
+		b.WriteString(m.fit("space: toggle  a: all  f: filter rule  d: delete  q: quit"))
+	}
+	return b.String()
+}
+
+func (m *Model) row(n *node, cursor bool) string {
+	prefix := "  "
+	if cursor {
+		prefix = "> "
+	}
+
+	check := "   "
+	switch {
+	case n.match != nil && n.selected:
+		check = "[x]"
+	case n.match != nil:
+		check = "[ ]"
+	case n.parent != nil && n.anySelected():
+		check = "[-]"
+	}
+
+	fold := " "
+	if len(n.children) > 0 && n.parent != nil {
+		fold = "+"
+		if n.expanded {
+			fold = "-"
+		}
+	}
+
+	name := n.name
+	if n.isDir() {
+		name += string(filepath.Separator)
+	}
+	left := prefix + strings.Repeat("  ", n.depth) + check + " " + fold + " " + name
+	size := ""
+	if n.size > 0 {
+		size = humanize.Bytes(n.size)
+	}
+	if pad := m.width - utf8.RuneCountInString(left) - sizeWidth; pad > 0 {
+		left += strings.Repeat(" ", pad)
+	} else {
+		left += " "
+	}
+	return m.fit(left + fmt.Sprintf("%*s", sizeWidth, size))
+}
+
+// fit truncates s to the width of the terminal.
+func (m *Model) fit(s string) string {
+	if m.width <= 0 || utf8.RuneCountInString(s) <= m.width {
+		return s
+	}
+	return string([]rune(s)[:m.width])
+}
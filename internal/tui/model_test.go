@@ -0,0 +1,160 @@
+package tui_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/echocrow/cleardir/internal/tui"
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/stretchr/testify/assert"
+)
+
+func newModel() *tui.Model {
+	return tui.New([]tui.Root{{Path: "/r", Matches: []cleardir.Match{
+		{Path: "/r/a/e", Kind: cleardir.KindDir, Depth: 1},
+		{Path: "/r/a/x", Kind: cleardir.KindFile, Size: 3, Rule: "x", Depth: 1},
+		{Path: "/r/a", Kind: cleardir.KindDir},
+		{Path: "/r/b/y", Kind: cleardir.KindFile, Size: 5, Rule: "y", Depth: 1},
+		{Path: "/r/z", Kind: cleardir.KindFile, Size: 2000, Rule: "x"},
+	}}}, 40, 10)
+}
+
+func update(m *tui.Model, keys ...tui.Key) {
+	for _, k := range keys {
+		m.Update(k)
+	}
+}
+
+func TestModelView(t *testing.T) {
+	m := newModel()
+	want := strings.Join([]string{
+		"5 of 5 selected (2.0 KiB) | rule: all",
+		">       /r/                      2.0 KiB",
+		"    [x] + a/                         3 B",
+		"    [-] - b/                         5 B",
+		"      [x]   y                        5 B",
+		"    [x]   z                      2.0 KiB",
+		"",
+		"",
+		"",
+		"space: toggle  a: all  f: filter rule  d",
+	}, "\n")
+	assert.Equal(t, want, m.View())
+}
+
+func TestModelToggle(t *testing.T) {
+	all := []string{"/r/a/e", "/r/a/x", "/r/a", "/r/b/y", "/r/z"}
+
+	tests := []struct {
+		name string
+		keys []tui.Key
+		want []string
+	}{
+		{"None", nil, all},
+		{"Dir", []tui.Key{tui.KeyDown, tui.KeySpace}, []string{"/r/b/y", "/r/z"}},
+		{"Dir Twice", []tui.Key{tui.KeyDown, tui.KeySpace, tui.KeySpace}, all},
+		{
+			"Child Keeps Ancestors",
+			[]tui.Key{tui.KeyDown, tui.KeyRight, tui.KeyDown, tui.KeySpace},
+			[]string{"/r/a/x", "/r/b/y", "/r/z"},
+		},
+		{
+			"Child Twice Restores Ancestors",
+			[]tui.Key{tui.KeyDown, tui.KeyRight, tui.KeyDown, tui.KeySpace, tui.KeySpace},
+			all,
+		},
+		{
+			"Non-Clearable Dir",
+			[]tui.Key{tui.KeyDown, tui.KeyDown, tui.KeySpace},
+			[]string{"/r/a/e", "/r/a/x", "/r/a", "/r/z"},
+		},
+		{"Root", []tui.Key{tui.KeySpace}, []string{}},
+		{"All", []tui.Key{"a"}, []string{}},
+		{"All Twice", []tui.Key{"a", "a"}, all},
+		{"Vim Keys", []tui.Key{"j", "l", "j", "j", tui.KeySpace}, []string{"/r/a/e", "/r/b/y", "/r/z"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newModel()
+			update(m, tc.keys...)
+			assert.Equal(t, tc.want, m.Selected())
+			assert.Equal(t, tui.ActionNone, m.Action())
+		})
+	}
+}
+
+func TestModelNavigate(t *testing.T) {
+	m := newModel()
+	update(m, tui.KeyDown, tui.KeyRight, tui.KeyDown, tui.KeyLeft)
+	assert.Contains(t, m.View(), "\n>   [x] - a/")
+
+	update(m, tui.KeyLeft)
+	assert.Contains(t, m.View(), "\n>   [x] + a/")
+
+	update(m, tui.KeyEnd)
+	assert.Contains(t, m.View(), "\n>   [x]   z")
+
+	update(m, tui.KeyHome, tui.KeyUp)
+	assert.Contains(t, m.View(), "\n>       /r/")
+}
+
+func TestModelScroll(t *testing.T) {
+	m := newModel()
+	m.Resize(40, 4)
+	update(m, tui.KeyEnd)
+	want := strings.Join([]string{
+		"5 of 5 selected (2.0 KiB) | rule: all",
+		"      [x]   y                        5 B",
+		">   [x]   z                      2.0 KiB",
+		"space: toggle  a: all  f: filter rule  d",
+	}, "\n")
+	assert.Equal(t, want, m.View())
+}
+
+func TestModelFilter(t *testing.T) {
+	m := newModel()
+
+	update(m, "f")
+	view := m.View()
+	assert.Contains(t, view, "rule: x")
+	assert.Contains(t, view, " a/")
+	assert.Contains(t, view, " z")
+	assert.NotContains(t, view, " b/")
+
+	update(m, "f")
+	view = m.View()
+	assert.Contains(t, view, "rule: y")
+	assert.Contains(t, view, " b/")
+	assert.NotContains(t, view, " a/")
+
+	update(m, "f")
+	assert.Contains(t, m.View(), "rule: all")
+}
+
+func TestModelActions(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []tui.Key
+		want tui.Action
+	}{
+		{"Delete", []tui.Key{"d", "y"}, tui.ActionDelete},
+		{"Delete Canceled", []tui.Key{"d", "n"}, tui.ActionNone},
+		{"Delete Nothing", []tui.Key{"a", "d", "y"}, tui.ActionNone},
+		{"Quit", []tui.Key{"q"}, tui.ActionQuit},
+		{"Esc", []tui.Key{tui.KeyEsc}, tui.ActionQuit},
+		{"Ctrl+C", []tui.Key{tui.KeyCtrlC}, tui.ActionQuit},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newModel()
+			update(m, tc.keys...)
+			assert.Equal(t, tc.want, m.Action())
+		})
+	}
+}
+
+func TestModelConfirmView(t *testing.T) {
+	m := newModel()
+	update(m, "d")
+	assert.True(t, strings.HasSuffix(m.View(), "\nDelete 5 selected entries? [y/N]"))
+}
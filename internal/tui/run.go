@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	clearScreen = "\x1b[H\x1b[2J"
+)
+
+// Run runs a session of m on the terminal connected to in and out until the
+// session ends, then restores the terminal.
+func Run(in, out *os.File, m *Model) error {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(in.Fd()), state)
+
+	fmt.Fprint(out, enterScreen)
+	defer fmt.Fprint(out, leaveScreen)
+
+	buf := make([]byte, 64)
+	for {
+		if w, h, err := term.GetSize(int(out.Fd())); err == nil && w > 0 && h > 0 {
+			m.Resize(w, h)
+		}
+		// Raw mode disables the translation of line feeds.
+		view := strings.ReplaceAll(m.View(), "\n", "\r\n")
+		fmt.Fprint(out, clearScreen+view)
+
+		if m.Action() != ActionNone {
+			return nil
+		}
+		n, err := in.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, k := range ParseKeys(buf[:n]) {
+			if m.Update(k); m.Action() != ActionNone {
+				break
+			}
+		}
+	}
+}
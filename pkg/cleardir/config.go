@@ -3,14 +3,69 @@ package cleardir
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"path/filepath"
 	"strings"
 
 	os "github.com/echocrow/osa"
 )
 
-func ParseClearables(custPath string) (
-	clearables []string,
+// ConfigHeader marks a configuration file with sections when it is the first
+// non-empty line of the file.
+const ConfigHeader = "# cleardir config v2"
+
+// Config is a parsed configuration file.
+//
+// Without ConfigHeader, each non-empty line lists a clearable file name.
+//
+// With ConfigHeader, empty lines and lines starting with "#" are ignored.
+// Lines before the first section header list clearable file names, where a
+// leading backslash escapes names starting with "#", "[", or "\". Each
+// "[kind]" or "[kind name]" header then starts a section of "key = value"
+// lines.
+type Config struct {
+	// Clearables lists file names that are safe for deletion.
+	Clearables []string
+	// Sections lists all sections in order of appearance.
+	Sections []Section
+}
+
+// Section is a named group of settings in a configuration file.
+type Section struct {
+	// Kind is the first word of the section header.
+	Kind string
+	// Name is the remainder of the section header, if any.
+	Name string
+	// Values maps keys to their values.
+	Values map[string]string
+}
+
+// Section returns the first unnamed section of the given kind.
+func (c Config) Section(kind string) (Section, bool) {
+	for _, sec := range c.Sections {
+		if sec.Kind == kind && sec.Name == "" {
+			return sec, true
+		}
+	}
+	return Section{}, false
+}
+
+// ConfigError describes a malformed configuration line.
+type ConfigError struct {
+	Path string
+	Line int
+	Msg  string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Msg)
+}
+
+// ReadConfig reads the configuration file at custPath, or at the default
+// path if custPath is empty. A missing default configuration file yields an
+// empty configuration.
+func ReadConfig(custPath string) (
+	cfg Config,
 	path string,
 	err error,
 ) {
@@ -23,16 +78,25 @@ func ParseClearables(custPath string) (
 		}
 	}
 
-	clearables, err = readCfgLines(path)
+	cfg, err = readCfg(path)
 
 	if useDefault && os.IsNotExist(err) {
-		clearables = []string{}
+		cfg = Config{Clearables: []string{}}
 		err = nil
 	}
 
 	return
 }
 
+func ParseClearables(custPath string) (
+	clearables []string,
+	path string,
+	err error,
+) {
+	cfg, path, err := ReadConfig(custPath)
+	return cfg.Clearables, path, err
+}
+
 func defaultCfgPath() (string, error) {
 	cfgDir, err := os.UserConfigDir()
 	if err != nil {
@@ -42,19 +106,60 @@ func defaultCfgPath() (string, error) {
 	return cfgPath, nil
 }
 
-func readCfgLines(path string) ([]string, error) {
+func readCfg(path string) (Config, error) {
+	cfg := Config{}
 	b, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return cfg, err
 	}
 	r := bytes.NewReader(b)
 	scanner := bufio.NewScanner(r)
-	lines := []string{}
-	for scanner.Scan() {
+	cfg.Clearables = []string{}
+	var sec *Section
+	// Plain lists of file names predate sections, and may list names like
+	// "[x]" or "#x".
+	hasSections, first := false, true
+	for n := 1; scanner.Scan(); n++ {
 		line := strings.TrimSpace(scanner.Text())
-		if line != "" {
-			lines = append(lines, line)
+		if line == "" {
+			continue
+		}
+		if first {
+			first = false
+			if line == ConfigHeader {
+				hasSections = true
+				continue
+			}
+		}
+		if !hasSections {
+			cfg.Clearables = append(cfg.Clearables, line)
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			header := strings.Fields(line[1 : len(line)-1])
+			if len(header) == 0 {
+				return cfg, &ConfigError{path, n, "empty section header"}
+			}
+			cfg.Sections = append(cfg.Sections, Section{
+				Kind:   header[0],
+				Name:   strings.Join(header[1:], " "),
+				Values: map[string]string{},
+			})
+			sec = &cfg.Sections[len(cfg.Sections)-1]
+			continue
+		}
+		if sec == nil {
+			cfg.Clearables = append(cfg.Clearables, strings.TrimPrefix(line, `\`))
+			continue
+		}
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			return cfg, &ConfigError{path, n, fmt.Sprintf("expected \"key = value\", got %q", line)}
 		}
+		sec.Values[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
 	}
-	return lines, nil
+	return cfg, nil
 }
@@ -47,6 +47,101 @@ func TestParseClearables(t *testing.T) {
 	}
 }
 
+func TestReadConfig(t *testing.T) {
+	os, reset := vos.Patch()
+	defer reset()
+
+	contents := heredoc.Doc(`
+		# cleardir config v2
+		# Junk.
+		.DS_Store
+		\[x]
+		\#y
+
+		[limits]
+		# Stay safe.
+		max-delete = 100
+		max-delete-size=1 GiB
+
+		[job docs]
+		path = /srv/docs
+		[job]
+	`)
+	want := cleardir.Config{
+		Clearables: []string{".DS_Store", "[x]", "#y"},
+		Sections: []cleardir.Section{
+			{Kind: "limits", Values: map[string]string{
+				"max-delete":      "100",
+				"max-delete-size": "1 GiB",
+			}},
+			{Kind: "job", Name: "docs", Values: map[string]string{"path": "/srv/docs"}},
+			{Kind: "job", Values: map[string]string{}},
+		},
+	}
+
+	tmpDir := vos.MkTempDir(os)
+	cfgPath := path.Join(tmpDir, "cfg")
+	testos.RequireWrite(t, os, cfgPath, contents)
+
+	got, _, err := cleardir.ReadConfig(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	limits, ok := got.Section("limits")
+	assert.True(t, ok)
+	assert.Equal(t, "100", limits.Values["max-delete"])
+	_, ok = got.Section("missing")
+	assert.False(t, ok)
+}
+
+func TestReadConfigLegacy(t *testing.T) {
+	os, reset := vos.Patch()
+	defer reset()
+
+	// Plain lists of file names read the same as before sections existed.
+	contents := heredoc.Doc(`
+		.DS_Store
+		[x]
+		# y
+		max-delete = 1
+	`)
+	tmpDir := vos.MkTempDir(os)
+	cfgPath := path.Join(tmpDir, "cfg")
+	testos.RequireWrite(t, os, cfgPath, contents)
+
+	got, _, err := cleardir.ReadConfig(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, cleardir.Config{
+		Clearables: []string{".DS_Store", "[x]", "# y", "max-delete = 1"},
+	}, got)
+}
+
+func TestReadConfigErr(t *testing.T) {
+	os, reset := vos.Patch()
+	defer reset()
+
+	tests := []struct {
+		name     string
+		contents string
+		wantLine int
+	}{
+		{"Empty Header", cleardir.ConfigHeader + "\na\n[ ]\n", 3},
+		{"Missing Value", cleardir.ConfigHeader + "\n[limits]\n\nmax-delete\n", 4},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := vos.MkTempDir(os)
+			cfgPath := path.Join(tmpDir, "cfg")
+			testos.RequireWrite(t, os, cfgPath, tc.contents)
+
+			_, _, err := cleardir.ReadConfig(cfgPath)
+			var cfgErr *cleardir.ConfigError
+			require.ErrorAs(t, err, &cfgErr)
+			assert.Equal(t, tc.wantLine, cfgErr.Line)
+		})
+	}
+}
+
 func TestParseClearablesErrNotExists(t *testing.T) {
 	tmpDir := t.TempDir()
 	cfgPath := path.Join(tmpDir, "missing")
@@ -1,57 +1,501 @@
 package cleardir
 
 import (
+	"context"
 	"io"
 	"path/filepath"
+	"sync"
+	"time"
 
 	os "github.com/echocrow/osa"
 	"github.com/scylladb/go-set/strset"
 )
 
+// Options configures a Finder.
+//
+// The zero value only matches files directly within the root, as a MaxDepth
+// of 0 never enters sub-directories. Start from DefaultOptions to scan the
+// whole tree instead.
+type Options struct {
+	// Trivials lists file names that are safe for deletion.
+	Trivials []string
+	// MaxDepth limits how many sub-directories to descend to at most; use -1
+	// for no limit. Zero only reads the root itself.
+	MaxDepth int
+	// OnError is called with any error encountered while reading a directory
+	// below the scan root. Returning nil skips the directory, which is then
+	// treated as non-empty; returning an error aborts the scan with that error.
+	// If OnError is nil, any error aborts the scan.
+	//
+	// Calls to OnError are never concurrent, even when Jobs exceeds 1.
+	OnError func(path string, err error) error
+	// Jobs limits how many directories are read concurrently. Values below 2
+	// scan sequentially.
+	Jobs int
+	// Sort ensures matches are sent in the same order as in a sequential scan,
+	// even when Jobs exceeds 1. Sorting may delay matches, as matches of a
+	// directory are then held back until all preceding entries are scanned.
+	Sort bool
+	// OnProgress is called with the overall scan progress after each
+	// directory that has been read. It should return quickly, as it blocks the
+	// scan; callers may want to throttle any expensive reporting.
+	//
+	// Calls to OnProgress are never concurrent, even when Jobs exceeds 1.
+	OnProgress func(p Progress)
+	// OnBlock is called with each entry that keeps its parent directory from
+	// being cleared, i.e. each non-trivial file, each directory beyond
+	// MaxDepth, each directory skipped via OnError, and each entry kept or
+	// skipped via Keep or Skip. Directories that are only blocked by their
+	// contents are not reported themselves.
+	//
+	// Calls to OnBlock are never concurrent, even when Jobs exceeds 1.
+	OnBlock func(b Blocker)
+	// ModifiedBefore, if set, only matches files and empty directories last
+	// modified before this time. More recently modified entries block their
+	// parent directories.
+	ModifiedBefore time.Time
+	// Keep, if set, is called with each entry that would otherwise match, and
+	// reports whether to keep it regardless. Kept entries block their parent
+	// directories.
+	//
+	// Keep may be called concurrently when Jobs exceeds 1.
+	Keep func(path string, isDir bool) bool
+	// Skip, if set, is called with each directory below the scan root before
+	// reading it, and reports whether to leave it alone entirely. Skipped
+	// directories are neither read nor matched, and block their parent
+	// directories.
+	//
+	// Skip may be called concurrently when Jobs exceeds 1.
+	Skip func(path string) bool
+}
+
+// DefaultOptions returns options that scan the whole tree, without a depth
+// limit.
+func DefaultOptions() Options {
+	return Options{MaxDepth: -1}
+}
+
+// Progress describes the progress of a running scan.
+type Progress struct {
+	// Dirs is the number of directories read so far.
+	Dirs int
+	// Matches is the number of matches found so far.
+	Matches int
+	// Path is the directory read most recently.
+	Path string
+}
+
+// Reason describes why an entry blocks its parent directory.
+type Reason int
+
+const (
+	// ReasonFile denotes a file that is not trivial.
+	ReasonFile Reason = iota
+	// ReasonDepth denotes a directory beyond the maximum depth.
+	ReasonDepth
+	// ReasonUnreadable denotes a directory skipped after a read error.
+	ReasonUnreadable
+	// ReasonRecent denotes an otherwise clearable entry that was modified too
+	// recently.
+	ReasonRecent
+	// ReasonProtected denotes an entry kept or skipped via Options.Keep or
+	// Options.Skip.
+	ReasonProtected
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonDepth:
+		return "beyond max depth"
+	case ReasonUnreadable:
+		return "unreadable"
+	case ReasonRecent:
+		return "recently modified"
+	case ReasonProtected:
+		return "protected"
+	}
+	return "non-trivial file"
+}
+
+// Blocker describes an entry that keeps its parent directory from being
+// cleared.
+type Blocker struct {
+	// Path is the path of the blocking entry.
+	Path string
+	// Kind is the kind of the blocking entry.
+	Kind Kind
+	// Reason is the reason the entry blocks its parent.
+	Reason Reason
+	// Depth is the number of directories between the scan root and the entry.
+	Depth int
+}
+
+// Kind describes the kind of a matched entry.
+type Kind int
+
+const (
+	// KindFile denotes a file.
+	KindFile Kind = iota
+	// KindDir denotes a directory.
+	KindDir
+)
+
+func (k Kind) String() string {
+	if k == KindDir {
+		return "dir"
+	}
+	return "file"
+}
+
+// Match describes a file or directory that can be safely deleted.
+type Match struct {
+	// Path is the path of the matched file or directory.
+	Path string
+	// Kind is the kind of the matched entry.
+	Kind Kind
+	// Size is the size of a matched file in bytes, or 0 for directories.
+	Size int64
+	// Rule is the trivial file name that matched a file, or empty for
+	// directories.
+	Rule string
+	// Depth is the number of directories between the scan root and the match,
+	// i.e. 0 for entries located directly inside the root.
+	Depth int
+}
+
+// Finder finds files and directories that can be safely deleted.
+type Finder struct {
+	opts     Options
+	trivials *strset.Set
+}
+
+// NewFinder creates a new Finder.
+func NewFinder(opts Options) *Finder {
+	return &Finder{
+		opts:     opts,
+		trivials: strset.New(opts.Trivials...),
+	}
+}
+
+// Find scans root for files and directories that can be safely deleted.
+//
+// Matches are sent in post-order, i.e. any directory is sent after all of its
+// contents. The matches channel is closed once the scan has ended, after
+// which the error channel yields the scan error, if any. Canceling ctx stops
+// the scan early with the context error.
+func (f *Finder) Find(ctx context.Context, root string) (<-chan Match, <-chan error) {
+	matches := make(chan Match)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		s := newScan(ctx, f, matches)
+		defer s.cancel()
+		_, err := s.find(s.send, root, 0)
+		if err != nil {
+			s.fail(err)
+			err = s.err
+		}
+		close(matches)
+		errc <- err
+	}()
+	return matches, errc
+}
+
 // FindClearables finds files and directories that can be safely deleted.
+//
+// Deprecated: Use Finder instead.
 func FindClearables(
 	matches chan<- string,
 	dir string,
 	trivials []string,
 	maxDepth int,
 ) error {
-	_, err := find(matches, strset.New(trivials...), dir, maxDepth)
-	return err
+	f := NewFinder(Options{
+		Trivials: trivials,
+		MaxDepth: maxDepth,
+	})
+	found, errc := f.Find(context.Background(), dir)
+	for m := range found {
+		matches <- m.Path
+	}
+	return <-errc
 }
 
-func find(
-	matches chan<- string,
-	trivials *strset.Set,
+func (f *Finder) canDescend(level int) bool {
+	return f.opts.MaxDepth < 0 || level < f.opts.MaxDepth
+}
+
+type emitFunc func(m Match) error
+
+// scan holds the state of a single Find run.
+type scan struct {
+	*Finder
+	ctx     context.Context
+	cancel  context.CancelFunc
+	out     chan<- Match
+	workers chan struct{}
+	errMu   sync.Mutex
+	errOnce sync.Once
+	err     error
+	progMu  sync.Mutex
+	prog    Progress
+	blockMu sync.Mutex
+}
+
+func newScan(ctx context.Context, f *Finder, out chan<- Match) *scan {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &scan{
+		Finder: f,
+		ctx:    ctx,
+		cancel: cancel,
+		out:    out,
+	}
+	if f.opts.Jobs > 1 {
+		s.workers = make(chan struct{}, f.opts.Jobs-1)
+	}
+	return s
+}
+
+func (s *scan) send(m Match) error {
+	select {
+	case s.out <- m:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// fail records the first error of the scan and stops any remaining work.
+func (s *scan) fail(err error) {
+	s.errOnce.Do(func() {
+		s.err = err
+		s.cancel()
+	})
+}
+
+func (s *scan) onError(path string, err error) error {
+	if s.opts.OnError == nil {
+		return err
+	}
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.opts.OnError(path, err)
+}
+
+// progress records progress and reports it if the directory at path has been
+// read.
+func (s *scan) progress(path string, matches int) {
+	if s.opts.OnProgress == nil {
+		return
+	}
+	s.progMu.Lock()
+	defer s.progMu.Unlock()
+	s.prog.Matches += matches
+	if path != "" {
+		s.prog.Dirs++
+		s.prog.Path = path
+		s.opts.OnProgress(s.prog)
+	}
+}
+
+func (s *scan) block(path string, kind Kind, reason Reason, level int) {
+	if s.opts.OnBlock == nil {
+		return
+	}
+	s.blockMu.Lock()
+	defer s.blockMu.Unlock()
+	s.opts.OnBlock(Blocker{Path: path, Kind: kind, Reason: reason, Depth: level})
+}
+
+func (s *scan) find(
+	emit emitFunc,
 	path string,
-	depth int,
+	level int,
 ) (
 	canDel bool,
 	err error,
 ) {
+	if err := s.ctx.Err(); err != nil {
+		return false, err
+	}
+
 	entries, dirErr := os.ReadDir(path)
 	if dirErr != nil && dirErr != io.EOF {
-		return false, dirErr
+		if level == 0 {
+			return false, dirErr
+		}
+		if err := s.onError(path, dirErr); err != nil {
+			return false, err
+		}
+		s.block(path, KindDir, ReasonUnreadable, level-1)
+		return false, nil
+	}
+	s.progress(path, 0)
+
+	if level > 0 && len(entries) == 0 && !s.isOldDir(path) {
+		s.block(path, KindDir, ReasonRecent, level-1)
+		return false, nil
+	}
+
+	subs := make([]*subScan, len(entries))
+	skipped := make([]bool, len(entries))
+	for i, e := range entries {
+		if e.IsDir() && s.opts.Skip != nil {
+			skipped[i] = s.opts.Skip(filepath.Join(path, e.Name()))
+		}
+	}
+	if s.canDescend(level) {
+		for i, e := range entries {
+			if e.IsDir() && !skipped[i] {
+				subs[i] = s.startSub(emit, filepath.Join(path, e.Name()), level+1)
+			}
+		}
 	}
 
 	canDel = true
-	for _, e := range entries {
+	for i, e := range entries {
+		if err != nil {
+			if subs[i] != nil {
+				subs[i].wait(nil)
+			}
+			continue
+		}
+
 		n := e.Name()
-		ep := filepath.Join(path, n)
+		m := Match{Path: filepath.Join(path, n), Depth: level}
 		del := false
-		if !e.IsDir() {
-			del = trivials.Has(n)
-		} else if depth != 0 {
-			del, err = find(matches, trivials, ep, depth-1)
+		if e.IsDir() {
+			m.Kind = KindDir
 		}
-		if err != nil {
-			return false, err
+		if subs[i] != nil {
+			del, err = subs[i].wait(emit)
+		} else if !e.IsDir() {
+			if del = s.trivials.Has(n); del {
+				m.Rule = n
+				m.Size = entrySize(e)
+			}
 		}
-		if del {
-			matches <- ep
-		} else {
-			canDel = false
+		kept := skipped[i] || (err == nil && del && s.opts.Keep != nil && s.opts.Keep(m.Path, m.Kind == KindDir))
+		if kept {
+			del = false
 		}
+		recent := err == nil && del && m.Kind == KindFile && !s.isOld(e)
+		if recent {
+			del = false
+		}
+		if err == nil {
+			if del {
+				s.progress("", 1)
+				err = emit(m)
+			} else {
+				canDel = false
+				switch {
+				case kept:
+					s.block(m.Path, m.Kind, ReasonProtected, level)
+				case recent:
+					s.block(m.Path, m.Kind, ReasonRecent, level)
+				case !e.IsDir():
+					s.block(m.Path, m.Kind, ReasonFile, level)
+				case subs[i] == nil:
+					s.block(m.Path, m.Kind, ReasonDepth, level)
+				}
+			}
+		}
+		if err != nil {
+			s.fail(err)
+		}
+	}
+	if err != nil {
+		return false, err
 	}
 
 	return
 }
+
+// subScan is a sub-directory scan that may run concurrently to its parent.
+type subScan struct {
+	s     *scan
+	path  string
+	level int
+	done  chan struct{}
+	buf   []Match
+	del   bool
+	err   error
+}
+
+// startSub starts scanning a sub-directory concurrently if a worker is
+// available. Otherwise the sub-directory is scanned once it is waited for.
+func (s *scan) startSub(emit emitFunc, path string, level int) *subScan {
+	sub := &subScan{s: s, path: path, level: level}
+	select {
+	case s.workers <- struct{}{}:
+	default:
+		return sub
+	}
+
+	sub.done = make(chan struct{})
+	if s.opts.Sort {
+		emit = sub.buffer
+	}
+	go func() {
+		defer func() { <-s.workers }()
+		sub.del, sub.err = s.find(emit, path, level)
+		close(sub.done)
+	}()
+	return sub
+}
+
+func (sub *subScan) buffer(m Match) error {
+	sub.buf = append(sub.buf, m)
+	return nil
+}
+
+// wait waits for the sub-directory scan to end, emitting any buffered matches.
+//
+// A nil emit only awaits concurrent scans and skips pending ones.
+func (sub *subScan) wait(emit emitFunc) (bool, error) {
+	if sub.done == nil {
+		if emit == nil {
+			return false, nil
+		}
+		return sub.s.find(emit, sub.path, sub.level)
+	}
+
+	<-sub.done
+	if sub.err != nil || emit == nil {
+		return false, sub.err
+	}
+	for _, m := range sub.buf {
+		if err := emit(m); err != nil {
+			return false, err
+		}
+	}
+	return sub.del, nil
+}
+
+// isOld reports whether e was last modified before the configured cutoff.
+func (s *scan) isOld(e os.DirEntry) bool {
+	if s.opts.ModifiedBefore.IsZero() {
+		return true
+	}
+	info, err := e.Info()
+	return err == nil && info.ModTime().Before(s.opts.ModifiedBefore)
+}
+
+// isOldDir reports whether the directory at path was last modified before the
+// configured cutoff.
+func (s *scan) isOldDir(path string) bool {
+	if s.opts.ModifiedBefore.IsZero() {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.ModTime().Before(s.opts.ModifiedBefore)
+}
+
+func entrySize(e os.DirEntry) int64 {
+	info, err := e.Info()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -1,12 +1,19 @@
 package cleardir_test
 
 import (
+	"context"
 	"fmt"
+	stdos "os"
 	"path"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/echocrow/cleardir/internal/ostest"
 	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/echocrow/osa/testos"
 	"github.com/echocrow/osa/vos"
+	"github.com/scylladb/go-set/strset"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -119,3 +126,456 @@ func TestFindClearablesErrInvalidDir(t *testing.T) {
 	assert.Error(t, err)
 	assert.Empty(t, gotMatches)
 }
+
+func TestFinderFind(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	err := fsd{
+		"d0": {"f0": nil},
+		"d1": {"f1": nil},
+		"f0": nil,
+	}.Write(dir)
+	require.NoError(t, err)
+
+	f := cleardir.NewFinder(cleardir.Options{
+		Trivials: []string{"f0"},
+		MaxDepth: -1,
+	})
+	matches, errc := f.Find(context.Background(), dir)
+	gotMatches := []string{}
+	for m := range matches {
+		gotMatches = append(gotMatches, m.Path)
+	}
+	assert.NoError(t, <-errc)
+
+	wantMatches := joinBaseDir(dir, []string{"d0/f0", "d0", "f0"})
+	assert.Equal(t, wantMatches, gotMatches)
+}
+
+func TestFinderFindCanceled(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	err := fsd{"d0": {}, "d1": {}, "d2": {}}.Write(dir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	f := cleardir.NewFinder(cleardir.DefaultOptions())
+	matches, errc := f.Find(ctx, dir)
+	<-matches
+	cancel()
+	for range matches {
+	}
+	assert.ErrorIs(t, <-errc, context.Canceled)
+}
+
+func TestFinderFindOnError(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := fsd{
+		"d0": {"sd": {}},
+		"d1": {"bad": {}, "sd": {}},
+		"d2": {},
+	}
+
+	tests := []struct {
+		name    string
+		onError func(path string, err error) error
+		want    []string
+		wantErr bool
+	}{
+		{"Abort", nil, []string{"d0/sd", "d0"}, true},
+		{"Skip", func(string, error) error { return nil }, []string{
+			"d0/sd", "d0", "d1/sd", "d2",
+		}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := vos.MkTempDir(v)
+			err := srcFsd.Write(dir)
+			require.NoError(t, err)
+
+			badDir := path.Join(dir, "d1", "bad")
+			defer ostest.PatchUnreadable(v, badDir)()
+
+			gotErrPaths := []string{}
+			onError := func(p string, err error) error {
+				gotErrPaths = append(gotErrPaths, p)
+				assert.ErrorIs(t, err, ostest.ErrUnreadable)
+				return tc.onError(p, err)
+			}
+			if tc.onError == nil {
+				onError = nil
+			}
+
+			f := cleardir.NewFinder(cleardir.Options{
+				MaxDepth: -1,
+				OnError:  onError,
+			})
+			matches, errc := f.Find(context.Background(), dir)
+			gotMatches := []string{}
+			for m := range matches {
+				gotMatches = append(gotMatches, m.Path)
+			}
+			err = <-errc
+
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ostest.ErrUnreadable)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, []string{badDir}, gotErrPaths)
+			}
+			assert.Equal(t, joinBaseDir(dir, tc.want), gotMatches)
+		})
+	}
+}
+
+func TestFinderFindBlockers(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	err := fsd{
+		"a": {"f0": nil, "keep": nil},
+		"b": {"bad": {}, "sd": {}},
+		"c": {"sd": {"deep": {}}},
+		"d": {"f0": nil},
+	}.Write(dir)
+	require.NoError(t, err)
+	defer ostest.PatchUnreadable(v, path.Join(dir, "b", "bad"))()
+
+	type blocker = cleardir.Blocker
+	got := []blocker{}
+	f := cleardir.NewFinder(cleardir.Options{
+		Trivials: []string{"f0"},
+		MaxDepth: 2,
+		OnError:  func(string, error) error { return nil },
+		OnBlock: func(b blocker) {
+			got = append(got, b)
+		},
+	})
+	matches, errc := f.Find(context.Background(), dir)
+	for range matches {
+	}
+	require.NoError(t, <-errc)
+
+	want := []blocker{
+		{Path: path.Join(dir, "a", "keep"), Reason: cleardir.ReasonFile, Depth: 1},
+		{Path: path.Join(dir, "b", "bad"), Kind: cleardir.KindDir, Reason: cleardir.ReasonUnreadable, Depth: 1},
+		{Path: path.Join(dir, "c", "sd", "deep"), Kind: cleardir.KindDir, Reason: cleardir.ReasonDepth, Depth: 2},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestFinderFindModifiedBefore(t *testing.T) {
+	// Use the real file system to control modification times.
+	dir := t.TempDir()
+	err := fsd{
+		"old":    fsd{"f0": nil, "e": fsd{}},
+		"new":    fsd{"f0": nil},
+		"newDir": fsd{},
+		"full":   fsd{"e": fsd{}},
+	}.Write(dir)
+	require.NoError(t, err)
+
+	cutoff := time.Now().Add(-time.Hour)
+	past := cutoff.Add(-time.Hour)
+	for _, p := range []string{"old/f0", "old/e", "full/e"} {
+		require.NoError(t, stdos.Chtimes(path.Join(dir, p), past, past))
+	}
+
+	got := []cleardir.Blocker{}
+	matches := findAll(t, cleardir.Options{
+		Trivials:       []string{"f0"},
+		MaxDepth:       -1,
+		ModifiedBefore: cutoff,
+		OnBlock: func(b cleardir.Blocker) {
+			got = append(got, b)
+		},
+	}, dir)
+
+	// Populated directories are matched regardless of their own times.
+	assert.Equal(t, joinBaseDir(dir, []string{"full/e", "full", "old/e", "old/f0", "old"}), matches)
+	assert.ElementsMatch(t, []cleardir.Blocker{
+		{Path: path.Join(dir, "new", "f0"), Reason: cleardir.ReasonRecent, Depth: 1},
+		{Path: path.Join(dir, "newDir"), Kind: cleardir.KindDir, Reason: cleardir.ReasonRecent, Depth: 0},
+	}, got)
+}
+
+func TestFinderFindKeepSkip(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	err := fsd{
+		"a":    {"f0": nil, "keep": {"f0": nil}},
+		"b":    {"f0": nil},
+		"skip": {"e": {}},
+		"e":    {},
+	}.Write(dir)
+	require.NoError(t, err)
+
+	got := []cleardir.Blocker{}
+	matches := findAll(t, cleardir.Options{
+		Trivials: []string{"f0"},
+		MaxDepth: -1,
+		Jobs:     4,
+		Sort:     true,
+		Keep: func(p string, isDir bool) bool {
+			return isDir && path.Base(p) == "keep" || p == path.Join(dir, "b", "f0")
+		},
+		Skip: func(p string) bool {
+			return path.Base(p) == "skip"
+		},
+		OnBlock: func(b cleardir.Blocker) {
+			got = append(got, b)
+		},
+	}, dir)
+
+	assert.Equal(t, joinBaseDir(dir, []string{"a/f0", "a/keep/f0", "e"}), matches)
+	assert.ElementsMatch(t, []cleardir.Blocker{
+		{Path: path.Join(dir, "a", "keep"), Kind: cleardir.KindDir, Reason: cleardir.ReasonProtected, Depth: 1},
+		{Path: path.Join(dir, "b", "f0"), Reason: cleardir.ReasonProtected, Depth: 1},
+		{Path: path.Join(dir, "skip"), Kind: cleardir.KindDir, Reason: cleardir.ReasonProtected, Depth: 0},
+	}, got)
+}
+
+func TestFinderFindOnErrorRoot(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	defer ostest.PatchUnreadable(v, dir)()
+
+	f := cleardir.NewFinder(cleardir.Options{
+		MaxDepth: -1,
+		OnError:  func(string, error) error { return nil },
+	})
+	matches, errc := f.Find(context.Background(), dir)
+	for range matches {
+	}
+	assert.ErrorIs(t, <-errc, ostest.ErrUnreadable)
+}
+
+func TestFinderFindJobs(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	srcFsd := genFsd(3, 4)
+	trivials := []string{"f0", "f1"}
+
+	dir := vos.MkTempDir(v)
+	err := srcFsd.Write(dir)
+	require.NoError(t, err)
+
+	want := findAll(t, cleardir.Options{Trivials: trivials, MaxDepth: -1}, dir)
+	require.NotEmpty(t, want)
+
+	for _, jobs := range []int{2, 4, 16} {
+		t.Run(fmt.Sprintf("%d jobs sorted", jobs), func(t *testing.T) {
+			got := findAll(t, cleardir.Options{
+				Trivials: trivials,
+				MaxDepth: -1,
+				Jobs:     jobs,
+				Sort:     true,
+			}, dir)
+			assert.Equal(t, want, got)
+		})
+
+		t.Run(fmt.Sprintf("%d jobs unsorted", jobs), func(t *testing.T) {
+			got := findAll(t, cleardir.Options{
+				Trivials: trivials,
+				MaxDepth: -1,
+				Jobs:     jobs,
+			}, dir)
+			assert.ElementsMatch(t, want, got)
+
+			seen := strset.New()
+			for _, p := range got {
+				assert.False(t, seen.Has(path.Dir(p)), "expected %s before its parent", p)
+				seen.Add(p)
+			}
+		})
+	}
+}
+
+func TestFinderFindProgress(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	err := fsd{
+		"d0": {"f0": nil},
+		"d1": {"f1": nil, "e": {}},
+		"f0": nil,
+	}.Write(dir)
+	require.NoError(t, err)
+
+	for _, jobs := range []int{1, 4} {
+		t.Run(fmt.Sprintf("%d jobs", jobs), func(t *testing.T) {
+			var active int32
+			got := []cleardir.Progress{}
+			f := cleardir.NewFinder(cleardir.Options{
+				Trivials: []string{"f0"},
+				MaxDepth: -1,
+				Jobs:     jobs,
+				OnProgress: func(p cleardir.Progress) {
+					assert.Equal(t, int32(1), atomic.AddInt32(&active, 1), "concurrent OnProgress")
+					got = append(got, p)
+					atomic.AddInt32(&active, -1)
+				},
+			})
+			matches, errc := f.Find(context.Background(), dir)
+			n := 0
+			for range matches {
+				n++
+			}
+			require.NoError(t, <-errc)
+
+			require.Len(t, got, 4)
+			gotDirs := []string{}
+			for i, p := range got {
+				assert.Equal(t, i+1, p.Dirs)
+				gotDirs = append(gotDirs, p.Path)
+			}
+			wantDirs := joinBaseDir(dir, []string{"", "d0", "d1", "d1/e"})
+			assert.ElementsMatch(t, wantDirs, gotDirs)
+			assert.Equal(t, 0, got[0].Matches)
+			assert.LessOrEqual(t, got[len(got)-1].Matches, n)
+		})
+	}
+}
+
+func TestFinderFindJobsOnError(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	err := genFsd(3, 3).Write(dir)
+	require.NoError(t, err)
+
+	bad := []string{path.Join(dir, "d0", "d1"), path.Join(dir, "d2", "d0", "d2")}
+	defer ostest.PatchUnreadable(v, bad...)()
+
+	t.Run("Abort", func(t *testing.T) {
+		f := cleardir.NewFinder(cleardir.Options{MaxDepth: -1, Jobs: 4})
+		matches, errc := f.Find(context.Background(), dir)
+		for range matches {
+		}
+		assert.ErrorIs(t, <-errc, ostest.ErrUnreadable)
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		gotErrPaths := []string{}
+		f := cleardir.NewFinder(cleardir.Options{
+			MaxDepth: -1,
+			Jobs:     4,
+			OnError: func(p string, err error) error {
+				gotErrPaths = append(gotErrPaths, p)
+				return nil
+			},
+		})
+		matches, errc := f.Find(context.Background(), dir)
+		for range matches {
+		}
+		assert.NoError(t, <-errc)
+		assert.ElementsMatch(t, bad, gotErrPaths)
+	})
+}
+
+func BenchmarkFinderFind(b *testing.B) {
+	dir := b.TempDir()
+	err := genFsd(6, 5).Write(dir)
+	require.NoError(b, err)
+
+	for _, jobs := range []int{1, 4, 16} {
+		for _, sort := range []bool{false, true} {
+			b.Run(fmt.Sprintf("jobs=%d/sort=%t", jobs, sort), func(b *testing.B) {
+				opts := cleardir.Options{
+					Trivials: []string{"f0"},
+					MaxDepth: -1,
+					Jobs:     jobs,
+					Sort:     sort,
+				}
+				for i := 0; i < b.N; i++ {
+					findAll(b, opts, dir)
+				}
+			})
+		}
+	}
+}
+
+// genFsd generates a tree of the given depth with n directories and n files
+// per directory. Trees at odd indexes are kept non-empty.
+func genFsd(depth, n int) fsd {
+	d := fsd{}
+	for i := 0; i < n; i++ {
+		d[fmt.Sprintf("f%d", i)] = nil
+		if depth > 0 {
+			d[fmt.Sprintf("d%d", i)] = genFsd(depth-1, n)
+		} else if i%2 == 1 {
+			d[fmt.Sprintf("keep%d", i)] = nil
+		}
+	}
+	return d
+}
+
+func findAll(t require.TestingT, opts cleardir.Options, dir string) []string {
+	f := cleardir.NewFinder(opts)
+	matches, errc := f.Find(context.Background(), dir)
+	got := []string{}
+	for m := range matches {
+		got = append(got, m.Path)
+	}
+	require.NoError(t, <-errc)
+	return got
+}
+
+func TestDefaultOptions(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	err := fsd{"f0": nil, "e": {}, "d": {"e": {}}}.Write(dir)
+	require.NoError(t, err)
+
+	// The zero value only reads the root, and never enters sub-directories.
+	opts := cleardir.Options{Trivials: []string{"f0"}}
+	assert.Equal(t, joinBaseDir(dir, []string{"f0"}), findAll(t, opts, dir))
+	opts = cleardir.DefaultOptions()
+	opts.Trivials = []string{"f0"}
+	assert.Equal(t, joinBaseDir(dir, []string{"d/e", "d", "e", "f0"}), findAll(t, opts, dir))
+}
+
+func TestFinderFindMatchInfo(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	err := fsd{"d": {"sd": {}}}.Write(dir)
+	require.NoError(t, err)
+	testos.RequireWrite(t, v, path.Join(dir, "d", "f"), "12345")
+
+	f := cleardir.NewFinder(cleardir.Options{
+		Trivials: []string{"f"},
+		MaxDepth: -1,
+	})
+	matches, errc := f.Find(context.Background(), dir)
+	got := []cleardir.Match{}
+	for m := range matches {
+		got = append(got, m)
+	}
+	require.NoError(t, <-errc)
+
+	want := []cleardir.Match{
+		{Path: path.Join(dir, "d/f"), Kind: cleardir.KindFile, Size: 5, Rule: "f", Depth: 1},
+		{Path: path.Join(dir, "d/sd"), Kind: cleardir.KindDir, Depth: 1},
+		{Path: path.Join(dir, "d"), Kind: cleardir.KindDir, Depth: 0},
+	}
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,54 @@
+package cleardir
+
+import (
+	"time"
+
+	os "github.com/echocrow/osa"
+)
+
+// Fingerprint identifies the state of a file or directory, so that changes
+// since it was matched can be detected.
+type Fingerprint struct {
+	// Kind is the kind of the entry.
+	Kind Kind
+	// Inode is the inode number of the entry, or 0 where unsupported.
+	Inode uint64
+	// Size is the size of the entry in bytes.
+	Size int64
+	// ModTime is the last modification time of the entry.
+	ModTime time.Time
+}
+
+// ReadFingerprint reads the fingerprint of the entry at path.
+//
+// Inodes are only read where the file info exposes them via Sys, so they are 0
+// on virtual file systems.
+func ReadFingerprint(path string) (Fingerprint, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	f := Fingerprint{
+		Inode:   inode(info),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if info.IsDir() {
+		f.Kind = KindDir
+	}
+	return f, nil
+}
+
+// Diff describes the first difference of f to an earlier fingerprint, or
+// returns an empty string if both match.
+func (f Fingerprint) Diff(earlier Fingerprint) string {
+	switch {
+	case f.Kind != earlier.Kind || f.Inode != earlier.Inode:
+		return "replaced"
+	case f.Size != earlier.Size:
+		return "size changed"
+	case !f.ModTime.Equal(earlier.ModTime):
+		return "modified"
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package cleardir_test
+
+import (
+	stdos "os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/echocrow/osa/testos"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	// Use the real file system for inode numbers and modification times.
+	dir := t.TempDir()
+	file := filepath.Join(dir, "f")
+	require.NoError(t, stdos.WriteFile(file, []byte("a"), 0o644))
+
+	read := func() cleardir.Fingerprint {
+		f, err := cleardir.ReadFingerprint(file)
+		require.NoError(t, err)
+		return f
+	}
+	orig := read()
+	assert.Equal(t, cleardir.KindFile, orig.Kind)
+	assert.Equal(t, int64(1), orig.Size)
+	assert.Equal(t, "", read().Diff(orig))
+
+	past := orig.ModTime.Add(-time.Hour)
+	require.NoError(t, stdos.Chtimes(file, past, past))
+	assert.Equal(t, "modified", read().Diff(orig))
+
+	require.NoError(t, stdos.WriteFile(file, []byte("ab"), 0o644))
+	assert.Equal(t, "size changed", read().Diff(orig))
+
+	other := filepath.Join(dir, "g")
+	require.NoError(t, stdos.WriteFile(other, []byte("a"), 0o644))
+	require.NoError(t, stdos.Chtimes(other, orig.ModTime, orig.ModTime))
+	require.NoError(t, stdos.Rename(other, file))
+	assert.Equal(t, "replaced", read().Diff(orig))
+
+	require.NoError(t, stdos.Remove(file))
+	require.NoError(t, stdos.Mkdir(file, 0o755))
+	got := read()
+	assert.Equal(t, cleardir.KindDir, got.Kind)
+	assert.Equal(t, "replaced", got.Diff(orig))
+
+	_, err := cleardir.ReadFingerprint(filepath.Join(dir, "missing"))
+	assert.True(t, stdos.IsNotExist(err))
+}
+
+func TestFingerprintVirtual(t *testing.T) {
+	v, reset := vos.Patch()
+	defer reset()
+
+	dir := vos.MkTempDir(v)
+	file := path.Join(dir, "f")
+	testos.RequireWrite(t, v, file, "abc")
+
+	got, err := cleardir.ReadFingerprint(file)
+	require.NoError(t, err)
+	assert.Equal(t, cleardir.KindFile, got.Kind)
+	assert.Equal(t, int64(3), got.Size)
+
+	got, err = cleardir.ReadFingerprint(dir)
+	require.NoError(t, err)
+	assert.Equal(t, cleardir.KindDir, got.Kind)
+}
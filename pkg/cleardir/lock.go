@@ -0,0 +1,266 @@
+package cleardir
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	stdos "os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	lockSuffix   = ".lock"
+	lockRegistry = "registry"
+)
+
+// LockedError reports a root that is locked by another run.
+type LockedError struct {
+	// Root is the root that was to be locked.
+	Root string
+	// Holder is the locked root overlapping Root.
+	Holder string
+	// PID is the process ID of the holder, if known.
+	PID int
+}
+
+func (e *LockedError) Error() string {
+	msg := fmt.Sprintf("%s is locked by another run", e.Root)
+	if e.PID > 0 {
+		msg = fmt.Sprintf("%s (pid %d)", msg, e.PID)
+	}
+	if e.Holder != e.Root {
+		msg += " on " + e.Holder
+	}
+	return msg
+}
+
+// Locker acquires advisory run locks on roots, so that concurrent runs never
+// clear the same tree.
+//
+// Locks are held via flock(2) on one file per canonical root. Roots conflict
+// if they are equal, or if either is nested inside the other. Locks are only
+// advisory, and are not supported on Windows, where they always succeed.
+type Locker struct {
+	// Dir is the directory of all lock files. It defaults to DefaultLockDir.
+	Dir string
+	// Wait waits for conflicting locks to be released, instead of failing with
+	// a LockedError.
+	Wait bool
+	// PollInterval is how often to check for released locks while waiting. It
+	// defaults to 100 milliseconds.
+	PollInterval time.Duration
+}
+
+// Lock is a set of held run locks.
+type Lock struct {
+	dir   string
+	files []*stdos.File
+}
+
+// DefaultLockDir returns the directory of lock files within the user runtime
+// directory, as given by XDG_RUNTIME_DIR. Without one, root uses "/run" if
+// present, and other users use the temporary directory.
+func DefaultLockDir() string {
+	if dir := stdos.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "cleardir")
+	}
+	uid := stdos.Getuid()
+	if uid == 0 {
+		if info, err := stdos.Stat("/run"); err == nil && info.IsDir() {
+			return "/run/cleardir"
+		}
+	}
+	return filepath.Join(stdos.TempDir(), fmt.Sprintf("cleardir-%d", uid))
+}
+
+// Lock locks all roots at once, or fails with a LockedError if any of them
+// overlaps with a root locked by another run. If Wait is set, Lock instead
+// waits until all roots are free, or until ctx is canceled.
+func (l Locker) Lock(ctx context.Context, roots ...string) (*Lock, error) {
+	dir := l.Dir
+	if dir == "" {
+		dir = DefaultLockDir()
+	}
+	if err := stdos.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	poll := l.PollInterval
+	if poll <= 0 {
+		poll = 100 * time.Millisecond
+	}
+
+	canon := make([]string, 0, len(roots))
+	seen := map[string]bool{}
+	for _, r := range roots {
+		c := canonicalRoot(r)
+		if !seen[c] {
+			seen[c] = true
+			canon = append(canon, c)
+		}
+	}
+
+	for {
+		lk, err := tryLock(dir, canon)
+		var le *LockedError
+		if !l.Wait || !errors.As(err, &le) {
+			return lk, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
+
+// Unlock releases all locks.
+func (lk *Lock) Unlock() error {
+	if lk == nil || len(lk.files) == 0 {
+		return nil
+	}
+	reg, err := lockFile(filepath.Join(lk.dir, lockRegistry), true)
+	if err != nil {
+		return err
+	}
+	defer reg.Close()
+	for _, f := range lk.files {
+		_ = stdos.Remove(f.Name())
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	lk.files = nil
+	return err
+}
+
+// tryLock locks all roots, unless any overlaps with a held lock.
+//
+// Locks are only inspected and acquired while holding the registry lock, so
+// that conflicting runs never both succeed.
+func tryLock(dir string, roots []string) (*Lock, error) {
+	reg, err := lockFile(filepath.Join(dir, lockRegistry), true)
+	if err != nil {
+		return nil, err
+	}
+	defer reg.Close()
+
+	entries, err := stdos.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), lockSuffix) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		held, pid, err := heldRoot(path)
+		if err != nil {
+			return nil, err
+		}
+		if held == "" {
+			continue
+		}
+		for _, r := range roots {
+			if r == held || IsNested(r, held) || IsNested(held, r) {
+				return nil, &LockedError{Root: r, Holder: held, PID: pid}
+			}
+		}
+	}
+
+	lk := &Lock{dir: dir}
+	for _, r := range roots {
+		f, err := lockFile(filepath.Join(dir, lockName(r)), false)
+		if err == nil {
+			err = writeLockInfo(f, r)
+		}
+		if err != nil {
+			if f != nil {
+				f.Close()
+			}
+			for _, f := range lk.files {
+				_ = stdos.Remove(f.Name())
+				f.Close()
+			}
+			return nil, err
+		}
+		lk.files = append(lk.files, f)
+	}
+	return lk, nil
+}
+
+// heldRoot returns the root and PID of the lock file at path if the lock is
+// held. Stale lock files are removed.
+func heldRoot(path string) (root string, pid int, err error) {
+	f, err := stdos.Open(path)
+	if stdos.IsNotExist(err) {
+		return "", 0, nil
+	} else if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	if err := flock(f, false); err == nil {
+		// The lock was abandoned without being released.
+		_ = stdos.Remove(path)
+		return "", 0, nil
+	} else if !errors.Is(err, errWouldBlock) {
+		return "", 0, err
+	}
+
+	b, err := stdos.ReadFile(path)
+	if err != nil {
+		return "", 0, err
+	}
+	// Holders write their info while holding the registry lock, so held
+	// locks always include it.
+	lines := strings.SplitN(strings.TrimSuffix(string(b), "\n"), "\n", 2)
+	if len(lines) < 2 {
+		return "", 0, nil
+	}
+	pid, _ = strconv.Atoi(lines[0])
+	return lines[1], pid, nil
+}
+
+// lockFile opens or creates the file at path and locks it exclusively,
+// optionally waiting for it to be released.
+func lockFile(path string, wait bool) (*stdos.File, error) {
+	f, err := stdos.OpenFile(path, stdos.O_RDWR|stdos.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := flock(f, wait); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func writeLockInfo(f *stdos.File, root string) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(f, "%d\n%s\n", stdos.Getpid(), root)
+	return err
+}
+
+// canonicalRoot resolves root to a clean absolute path without symbolic
+// links, as far as possible.
+func canonicalRoot(root string) string {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	return root
+}
+
+func lockName(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return hex.EncodeToString(sum[:16]) + lockSuffix
+}
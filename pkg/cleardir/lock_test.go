@@ -0,0 +1,123 @@
+package cleardir_test
+
+import (
+	"context"
+	stdos "os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocker(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	require.NoError(t, stdos.MkdirAll(filepath.Join(root, "sub"), 0o755))
+	require.NoError(t, stdos.Mkdir(filepath.Join(dir, "other"), 0o755))
+	require.NoError(t, stdos.Symlink(root, filepath.Join(dir, "link")))
+
+	l := cleardir.Locker{Dir: filepath.Join(dir, "locks")}
+	ctx := context.Background()
+
+	held, err := l.Lock(ctx, root)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		root       string
+		wantHolder string
+	}{
+		{"Same", root, root},
+		{"Symlink", filepath.Join(dir, "link"), root},
+		{"Child", filepath.Join(root, "sub"), root},
+		{"Parent", dir, root},
+		{"Sibling", filepath.Join(dir, "other"), ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lk, err := l.Lock(ctx, filepath.Join(dir, "other"), tc.root)
+			if tc.wantHolder == "" {
+				require.NoError(t, err)
+				assert.NoError(t, lk.Unlock())
+				return
+			}
+			var le *cleardir.LockedError
+			require.ErrorAs(t, err, &le)
+			assert.Equal(t, tc.wantHolder, le.Holder)
+			assert.Equal(t, stdos.Getpid(), le.PID)
+			assert.Nil(t, lk)
+		})
+	}
+
+	require.NoError(t, held.Unlock())
+	lk, err := l.Lock(ctx, filepath.Join(root, "sub"))
+	require.NoError(t, err)
+	assert.NoError(t, lk.Unlock())
+}
+
+func TestLockerWait(t *testing.T) {
+	dir := t.TempDir()
+	l := cleardir.Locker{Dir: dir, Wait: true, PollInterval: time.Millisecond}
+
+	held, err := l.Lock(context.Background(), "/some/root")
+	require.NoError(t, err)
+
+	got := make(chan error, 1)
+	go func() {
+		lk, err := l.Lock(context.Background(), "/some/root/sub")
+		if err == nil {
+			err = lk.Unlock()
+		}
+		got <- err
+	}()
+	select {
+	case err := <-got:
+		t.Fatalf("lock acquired while held: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	require.NoError(t, held.Unlock())
+	assert.NoError(t, <-got)
+}
+
+func TestLockerWaitCanceled(t *testing.T) {
+	dir := t.TempDir()
+	l := cleardir.Locker{Dir: dir, Wait: true, PollInterval: time.Millisecond}
+
+	held, err := l.Lock(context.Background(), "/some/root")
+	require.NoError(t, err)
+	defer held.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.Lock(ctx, "/some/root")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLockerStale(t *testing.T) {
+	dir := t.TempDir()
+	l := cleardir.Locker{Dir: dir}
+
+	// A lock file left behind by a crashed run is not held.
+	held, err := l.Lock(context.Background(), "/some/root")
+	require.NoError(t, err)
+	entries, err := stdos.ReadDir(dir)
+	require.NoError(t, err)
+	stale := map[string][]byte{}
+	for _, e := range entries {
+		b, err := stdos.ReadFile(filepath.Join(dir, e.Name()))
+		require.NoError(t, err)
+		stale[e.Name()] = b
+	}
+	require.NoError(t, held.Unlock())
+	for name, b := range stale {
+		require.NoError(t, stdos.WriteFile(filepath.Join(dir, name), b, 0o600))
+	}
+
+	lk, err := l.Lock(context.Background(), "/some/root")
+	require.NoError(t, err)
+	assert.NoError(t, lk.Unlock())
+}
@@ -0,0 +1,13 @@
+package cleardir
+
+import (
+	"errors"
+	stdos "os"
+)
+
+var errWouldBlock = errors.New("lock would block")
+
+// flock is a no-op, as run locks are not supported on Windows.
+func flock(f *stdos.File, wait bool) error {
+	return nil
+}
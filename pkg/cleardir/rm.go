@@ -1,15 +1,122 @@
 package cleardir
 
 import (
+	"context"
+	"path/filepath"
+
 	os "github.com/echocrow/osa"
 )
 
 // Remove removes all listed files and directories.
 func Remove(paths ...string) error {
-	for _, p := range paths {
-		if err := os.Remove(p); err != nil {
-			return err
+	return Remover{}.Remove(context.Background(), paths...)
+}
+
+// Remover removes files and directories, optionally concurrently.
+type Remover struct {
+	// Jobs limits how many paths are removed concurrently. Values below 2
+	// remove sequentially.
+	Jobs int
+	// OnRemove is called after each attempted removal with the removal error,
+	// if any. Calls to OnRemove are never concurrent.
+	OnRemove func(path string, err error)
+	// OnProgress is called after each attempted removal with the number of
+	// attempted removals so far and the total number of paths. Calls to
+	// OnProgress are never concurrent.
+	OnProgress func(done, total int)
+}
+
+type removal struct {
+	i       int
+	err     error
+	skipped bool
+}
+
+// Remove removes all listed files and directories.
+//
+// Any listed directory is only removed once all of its listed contents have
+// been removed, so paths may be passed in any order. Removal stops at the
+// first error, or once ctx is canceled, and returns that error. Removals
+// already in progress are awaited.
+func (r Remover) Remove(ctx context.Context, paths ...string) error {
+	n := len(paths)
+	if n == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	index := make(map[string]int, n)
+	for i, p := range paths {
+		index[filepath.Clean(p)] = i
+	}
+	parents := make([]int, n)
+	pending := make([]int, n)
+	for i, p := range paths {
+		parents[i] = -1
+		if j, ok := index[filepath.Dir(filepath.Clean(p))]; ok && j != i {
+			parents[i] = j
+			pending[j]++
 		}
 	}
-	return nil
+
+	ready := make(chan int, n)
+	done := make(chan removal)
+	inFlight := 0
+	for i := range paths {
+		if pending[i] == 0 {
+			ready <- i
+			inFlight++
+		}
+	}
+
+	jobs := r.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	for w := 0; w < jobs; w++ {
+		go func() {
+			for i := range ready {
+				if err := ctx.Err(); err != nil {
+					done <- removal{i, err, true}
+					continue
+				}
+				done <- removal{i, os.Remove(paths[i]), false}
+			}
+		}()
+	}
+
+	var firstErr error
+	attempted := 0
+	for inFlight > 0 {
+		res := <-done
+		inFlight--
+		if !res.skipped {
+			attempted++
+			if r.OnRemove != nil {
+				r.OnRemove(paths[res.i], res.err)
+			}
+			if r.OnProgress != nil {
+				r.OnProgress(attempted, n)
+			}
+		}
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+				cancel()
+			}
+			continue
+		}
+		if p := parents[res.i]; p >= 0 && firstErr == nil {
+			pending[p]--
+			if pending[p] == 0 {
+				ready <- p
+				inFlight++
+			}
+		}
+	}
+	close(ready)
+
+	return firstErr
 }
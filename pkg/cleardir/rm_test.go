@@ -1,6 +1,7 @@
 package cleardir_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -78,3 +79,148 @@ func TestRemoveErr(t *testing.T) {
 		})
 	}
 }
+
+func TestRemover(t *testing.T) {
+	// Use the real file system, as concurrent writes to the virtual one are not
+	// thread-safe.
+	srcFsd := fsd{
+		"d": fsd{
+			"s0": nil,
+			"s1": nil,
+			"sd": fsd{"a": nil, "b": nil},
+		},
+		"f": nil,
+	}
+	allRms := rms{"d/s0", "d/s1", "d/sd/a", "d/sd/b", "d/sd", "d", "f"}
+
+	tests := []struct {
+		name string
+		rms  rms
+		want fsd
+	}{
+		{"None", nil, srcFsd},
+		{"Post-Order", allRms, fsd{}},
+		{"Pre-Order", rms{"d", "d/sd", "d/sd/b", "d/sd/a", "f", "d/s1", "d/s0"}, fsd{}},
+		{"Partial", rms{"d/sd", "d/sd/a", "d/sd/b", "f"}, fsd{"d": fsd{"s0": nil, "s1": nil}}},
+	}
+	for _, jobs := range []int{0, 1, 4} {
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%d jobs %s", jobs, tc.name), func(t *testing.T) {
+				tmpDir := t.TempDir()
+				err := srcFsd.Write(tmpDir)
+				require.NoError(t, err)
+
+				rms := joinBaseDir(tmpDir, tc.rms)
+
+				gotRemoved := []string{}
+				r := cleardir.Remover{
+					Jobs: jobs,
+					OnRemove: func(p string, err error) {
+						assert.NoError(t, err)
+						gotRemoved = append(gotRemoved, p)
+					},
+				}
+				gotErr := r.Remove(context.Background(), rms...)
+				assert.NoError(t, gotErr)
+				assert.ElementsMatch(t, rms, gotRemoved)
+
+				gotFsd, fsdErr := dirsnap.Read(tmpDir, -1)
+				require.NoError(t, fsdErr)
+				assert.Equal(t, tc.want, gotFsd)
+			})
+		}
+	}
+}
+
+func TestRemoverProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := fsd{"d": fsd{"a": nil, "b": nil}, "f": nil}.Write(tmpDir)
+	require.NoError(t, err)
+
+	rms := joinBaseDir(tmpDir, rms{"d/a", "d/b", "d", "f"})
+	got := []int{}
+	r := cleardir.Remover{
+		Jobs: 2,
+		OnProgress: func(done, total int) {
+			assert.Equal(t, len(rms), total)
+			got = append(got, done)
+		},
+	}
+	err = r.Remove(context.Background(), rms...)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4}, got)
+}
+
+func TestRemoverErr(t *testing.T) {
+	srcFsd := fsd{
+		"a": fsd{"f": nil, "keep": nil},
+		"b": fsd{"f": nil},
+	}
+	rmNames := rms{"a/f", "a", "b/f", "b"}
+
+	for _, jobs := range []int{1, 4} {
+		t.Run(fmt.Sprint(jobs), func(t *testing.T) {
+			tmpDir := t.TempDir()
+			err := srcFsd.Write(tmpDir)
+			require.NoError(t, err)
+
+			rms := joinBaseDir(tmpDir, rmNames)
+
+			gotErrs := []string{}
+			r := cleardir.Remover{
+				Jobs: jobs,
+				OnRemove: func(p string, err error) {
+					if err != nil {
+						gotErrs = append(gotErrs, p)
+					}
+				},
+			}
+			gotErr := r.Remove(context.Background(), rms...)
+			assert.Error(t, gotErr)
+			assert.Equal(t, joinBaseDir(tmpDir, []string{"a"}), gotErrs)
+
+			gotFsd, fsdErr := dirsnap.Read(tmpDir, -1)
+			require.NoError(t, fsdErr)
+			assert.Equal(t, fsd{"keep": nil}, gotFsd["a"])
+		})
+	}
+}
+
+func TestRemoverCanceled(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := fsd{"f": nil}.Write(tmpDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := cleardir.Remover{Jobs: 4}
+	gotErr := r.Remove(ctx, joinBaseDir(tmpDir, rms{"f"})...)
+	assert.ErrorIs(t, gotErr, context.Canceled)
+
+	gotFsd, fsdErr := dirsnap.Read(tmpDir, -1)
+	require.NoError(t, fsdErr)
+	assert.Equal(t, fsd{"f": nil}, gotFsd)
+}
+
+func BenchmarkRemover(b *testing.B) {
+	srcFsd := genFsd(4, 5)
+	for _, jobs := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				dir := b.TempDir()
+				err := srcFsd.Write(dir)
+				require.NoError(b, err)
+				rms := findAll(b, cleardir.Options{
+					Trivials: []string{"f0", "f1", "f2", "f3", "f4", "keep1", "keep3"},
+					MaxDepth: -1,
+				}, dir)
+				b.StartTimer()
+
+				err = cleardir.Remover{Jobs: jobs}.Remove(context.Background(), rms...)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package cleardir
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// DedupeRoots removes duplicate roots and roots nested inside other roots.
+//
+// Roots are cleaned but otherwise kept in their original order. Relative and
+// absolute paths are not reconciled, so callers should pass absolute paths.
+func DedupeRoots(roots []string) []string {
+	cleaned := make([]string, len(roots))
+	for i, r := range roots {
+		cleaned[i] = filepath.Clean(r)
+	}
+
+	out := []string{}
+	for i, r := range cleaned {
+		keep := true
+		for j, o := range cleaned {
+			if i == j {
+				continue
+			}
+			if (r == o && j < i) || (r != o && IsNested(o, r)) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// IsNested reports whether path is located inside of (but not equal to) dir.
+//
+// Both paths are expected to be clean.
+func IsNested(dir, path string) bool {
+	if dir == path {
+		return false
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
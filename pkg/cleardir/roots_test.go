@@ -0,0 +1,55 @@
+package cleardir_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupeRoots(t *testing.T) {
+	tests := []struct {
+		roots []string
+		want  []string
+	}{
+		{nil, []string{}},
+		{[]string{"/a"}, []string{"/a"}},
+		{[]string{"/a", "/b"}, []string{"/a", "/b"}},
+		{[]string{"/b", "/a"}, []string{"/b", "/a"}},
+		{[]string{"/a", "/a"}, []string{"/a"}},
+		{[]string{"/a", "/a/"}, []string{"/a"}},
+		{[]string{"/a", "/a/b"}, []string{"/a"}},
+		{[]string{"/a/b", "/a"}, []string{"/a"}},
+		{[]string{"/a/b", "/c", "/a", "/a/b/c"}, []string{"/c", "/a"}},
+		{[]string{"/a", "/ab"}, []string{"/a", "/ab"}},
+		{[]string{"/", "/a"}, []string{"/"}},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			got := cleardir.DedupeRoots(tc.roots)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestIsNested(t *testing.T) {
+	tests := []struct {
+		dir  string
+		path string
+		want bool
+	}{
+		{"/a", "/a", false},
+		{"/a", "/a/b", true},
+		{"/a", "/a/b/c", true},
+		{"/a", "/ab", false},
+		{"/a/b", "/a", false},
+		{"/", "/a", true},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			got := cleardir.IsNested(tc.dir, tc.path)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
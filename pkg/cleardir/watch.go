@@ -0,0 +1,309 @@
+package cleardir
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"time"
+
+	os "github.com/echocrow/osa"
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultGrace is the grace period of directories, unless configured
+// otherwise.
+const DefaultGrace = 2 * time.Second
+
+// Watcher clears files and directories below a root directory as they become
+// clearable.
+type Watcher struct {
+	// Options configures how directories are scanned. MaxDepth,
+	// ModifiedBefore, and OnBlock are ignored.
+	Options Options
+	// MinAge only clears files and empty directories last modified at least
+	// this long ago. Younger entries are re-evaluated once they are old
+	// enough.
+	MinAge time.Duration
+	// Grace only clears directories last modified at least this long ago, as
+	// new directories are usually filled right after being created. Unlike
+	// MinAge, it does not apply to files. Zero denotes DefaultGrace, and
+	// negative values denote no grace period.
+	Grace time.Duration
+	// Debounce is how long to wait for further changes before re-evaluating
+	// changed directories.
+	Debounce time.Duration
+	// Remover removes clearable entries. Removal errors do not stop the
+	// watcher, and may be observed via the remover's OnRemove.
+	Remover Remover
+	// OnScan is called before re-evaluating a changed directory.
+	OnScan func(path string)
+}
+
+// Watch watches root for changes and clears any entries that became
+// clearable, until ctx is canceled. Initially, all of root is evaluated.
+//
+// Only the directories affected by a change are re-evaluated, along with any
+// ancestors that become clearable in turn. The root itself is never removed.
+// Errors while reading a changed directory itself are passed to the
+// OnError option like errors of any sub-directories, or stop the watcher if
+// OnError is nil. Watch returns the context error once ctx is canceled, or
+// any error that prevents further watching.
+func (w *Watcher) Watch(ctx context.Context, root string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	root = filepath.Clean(root)
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+	if err := addWatches(fw, root); err != nil {
+		return err
+	}
+
+	dirty := map[string]bool{root: true}
+	debounce := time.NewTimer(0)
+	defer debounce.Stop()
+
+	// Each directory to re-evaluate later has a single pending timer, and
+	// only the latest timer of a directory counts as fired.
+	type recheck struct {
+		dir string
+		gen int
+	}
+	type pending struct {
+		gen   int
+		timer *time.Timer
+	}
+	rechecks := make(chan recheck)
+	timers := map[string]pending{}
+	defer func() {
+		for _, p := range timers {
+			p.timer.Stop()
+		}
+	}()
+	gen := 0
+	schedule := func(dir string, d time.Duration) {
+		if p, ok := timers[dir]; ok {
+			p.timer.Stop()
+		}
+		gen++
+		rc := recheck{dir, gen}
+		timers[dir] = pending{gen, time.AfterFunc(d, func() {
+			select {
+			case rechecks <- rc:
+			case <-ctx.Done():
+			}
+		})}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-fw.Events:
+			if !ok {
+				return closedErr(ctx)
+			}
+			if w.handleEvent(fw, root, ev, dirty) {
+				resetTimer(debounce, w.Debounce)
+			}
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return closedErr(ctx)
+			}
+			if err != fsnotify.ErrEventOverflow {
+				return err
+			}
+			// Missed events may have affected any directory.
+			dirty[root] = true
+			resetTimer(debounce, w.Debounce)
+		case rc := <-rechecks:
+			if timers[rc.dir].gen == rc.gen {
+				delete(timers, rc.dir)
+			}
+			dirty[rc.dir] = true
+			resetTimer(debounce, w.Debounce)
+		case <-debounce.C:
+			dirs := make([]string, 0, len(dirty))
+			for d := range dirty {
+				dirs = append(dirs, d)
+			}
+			dirty = map[string]bool{}
+			sort.Strings(dirs)
+			for _, d := range DedupeRoots(dirs) {
+				recent, wait, err := w.clear(ctx, root, d)
+				if err != nil {
+					return err
+				}
+				if recent != "" {
+					schedule(recent, wait)
+				}
+			}
+		}
+	}
+}
+
+// errWatcherClosed reports that the underlying file system watcher stopped
+// unexpectedly.
+var errWatcherClosed = errors.New("watcher closed")
+
+// closedErr returns the error of a watch whose file system watcher stopped.
+func closedErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errWatcherClosed
+}
+
+func (w *Watcher) grace() time.Duration {
+	switch {
+	case w.Grace == 0:
+		return DefaultGrace
+	case w.Grace < 0:
+		return 0
+	}
+	return w.Grace
+}
+
+// handleEvent marks all directories affected by ev as dirty, and reports
+// whether any were.
+func (w *Watcher) handleEvent(fw *fsnotify.Watcher, root string, ev fsnotify.Event, dirty map[string]bool) bool {
+	if ev.Op == fsnotify.Chmod {
+		return false
+	}
+	path := filepath.Clean(ev.Name)
+	if ev.Op&fsnotify.Create != 0 {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			// Entries may have been created before the directory was watched.
+			_ = addWatches(fw, path)
+			dirty[path] = true
+		}
+	}
+	if path != root {
+		dirty[filepath.Dir(path)] = true
+	}
+	return true
+}
+
+// clear evaluates dir and any ancestors that are clearable as a result, and
+// removes all clearable entries. If any entries were too recent, the
+// directory to re-evaluate later is returned, along with when to do so.
+func (w *Watcher) clear(ctx context.Context, root, dir string) (recent string, wait time.Duration, err error) {
+	var res watchScan
+	for {
+		res, err = w.scan(ctx, dir)
+		if os.IsNotExist(err) && dir != root {
+			dir = filepath.Dir(dir)
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil || w.Options.OnError == nil {
+				return "", 0, err
+			}
+			return "", 0, w.Options.OnError(dir, err)
+		}
+		if res.blocked || dir == root {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	if res.wait > 0 {
+		recent = dir
+	}
+	if err := w.Remover.Remove(ctx, res.paths...); err != nil && ctx.Err() != nil {
+		return "", 0, err
+	}
+	return recent, res.wait, nil
+}
+
+type watchScan struct {
+	paths   []string
+	blocked bool
+	// wait is how long until recent entries may be clearable, if any.
+	wait time.Duration
+}
+
+func (w *Watcher) scan(ctx context.Context, dir string) (watchScan, error) {
+	if w.OnScan != nil {
+		w.OnScan(dir)
+	}
+	res := watchScan{paths: []string{}}
+	opts := w.Options
+	opts.MaxDepth = -1
+	opts.ModifiedBefore = time.Time{}
+	if w.MinAge > 0 {
+		opts.ModifiedBefore = time.Now().Add(-w.MinAge)
+	}
+	// Directories in their grace period are kept like protected ones.
+	now, grace := time.Now(), w.grace()
+	young := func(path string) (time.Duration, bool) {
+		if grace <= 0 {
+			return 0, false
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, false
+		}
+		wait := info.ModTime().Add(grace).Sub(now)
+		return wait, wait > 0
+	}
+	keep := opts.Keep
+	opts.Keep = func(path string, isDir bool) bool {
+		if isDir {
+			if _, ok := young(path); ok {
+				return true
+			}
+		}
+		return keep != nil && keep(path, isDir)
+	}
+	opts.OnBlock = func(b Blocker) {
+		res.blocked = true
+		wait := time.Duration(0)
+		switch {
+		case b.Reason == ReasonRecent:
+			wait = w.MinAge
+		case b.Reason == ReasonProtected && b.Kind == KindDir:
+			wait, _ = young(b.Path)
+		}
+		if wait > res.wait {
+			res.wait = wait
+		}
+	}
+	matches, errc := NewFinder(opts).Find(ctx, dir)
+	for m := range matches {
+		res.paths = append(res.paths, m.Path)
+	}
+	return res, <-errc
+}
+
+// addWatches watches dir and all directories below it.
+func addWatches(fw *fsnotify.Watcher, dir string) error {
+	if err := fw.Add(dir); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			if err := addWatches(fw, filepath.Join(dir, e.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
@@ -0,0 +1,155 @@
+package cleardir_test
+
+import (
+	"context"
+	stdos "os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/echocrow/cleardir/pkg/cleardir"
+	"github.com/echocrow/fsnap/dirsnap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startWatcher runs w on dir in the background, and returns a function that
+// stops it and returns its error.
+func startWatcher(t *testing.T, w *cleardir.Watcher, dir string) func() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	scanned := make(chan struct{})
+	var once sync.Once
+	onScan := w.OnScan
+	w.OnScan = func(p string) {
+		once.Do(func() { close(scanned) })
+		if onScan != nil {
+			onScan(p)
+		}
+	}
+	go func() { errc <- w.Watch(ctx, dir) }()
+	select {
+	case <-scanned:
+	case err := <-errc:
+		require.NoError(t, err)
+	}
+	return func() error {
+		cancel()
+		return <-errc
+	}
+}
+
+func requireEventuallyFsd(t *testing.T, dir string, want fsd) {
+	var got fsd
+	ok := assert.Eventually(t, func() bool {
+		var err error
+		got, err = dirsnap.Read(dir, -1)
+		return err == nil && assert.ObjectsAreEqual(want, got)
+	}, 500*time.Millisecond, 5*time.Millisecond)
+	if !ok {
+		assert.Equal(t, want, got)
+		t.FailNow()
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	// Use the real file system, as the virtual one does not emit events.
+	dir := t.TempDir()
+	err := fsd{
+		"empty": fsd{},
+		"k":     fsd{"keep": nil, "f0": nil, "sub": fsd{"keep": nil}},
+	}.Write(dir)
+	require.NoError(t, err)
+
+	removed := make(chan string, 32)
+	w := &cleardir.Watcher{
+		Options:  cleardir.Options{Trivials: []string{"f0"}},
+		Grace:    50 * time.Millisecond,
+		Debounce: 5 * time.Millisecond,
+		Remover: cleardir.Remover{OnRemove: func(p string, err error) {
+			assert.NoError(t, err)
+			removed <- p
+		}},
+	}
+	stop := startWatcher(t, w, dir)
+
+	// Initial pass.
+	requireEventuallyFsd(t, dir, fsd{"k": fsd{"keep": nil, "sub": fsd{"keep": nil}}})
+
+	// New junk files.
+	err = stdos.WriteFile(path.Join(dir, "k", "sub", "f0"), nil, 0600)
+	require.NoError(t, err)
+	requireEventuallyFsd(t, dir, fsd{"k": fsd{"keep": nil, "sub": fsd{"keep": nil}}})
+
+	// Directories that became empty, along with their ancestors.
+	require.NoError(t, stdos.Remove(path.Join(dir, "k", "sub", "keep")))
+	requireEventuallyFsd(t, dir, fsd{"k": fsd{"keep": nil}})
+	require.NoError(t, stdos.Remove(path.Join(dir, "k", "keep")))
+	requireEventuallyFsd(t, dir, fsd{})
+
+	// New directory trees, created step by step.
+	err = fsd{"n": fsd{"d": fsd{"f0": nil}}}.Write(dir)
+	require.NoError(t, err)
+	requireEventuallyFsd(t, dir, fsd{})
+
+	assert.ErrorIs(t, stop(), context.Canceled)
+	close(removed)
+	got := []string{}
+	for p := range removed {
+		got = append(got, p)
+	}
+	assert.Contains(t, got, path.Join(dir, "empty"))
+	assert.Contains(t, got, path.Join(dir, "k"))
+}
+
+func TestWatcherMinAge(t *testing.T) {
+	dir := t.TempDir()
+
+	w := &cleardir.Watcher{
+		MinAge:   100 * time.Millisecond,
+		Grace:    -1,
+		Debounce: 5 * time.Millisecond,
+	}
+	stop := startWatcher(t, w, dir)
+
+	err := fsd{"new": fsd{}}.Write(dir)
+	require.NoError(t, err)
+	created := time.Now()
+
+	requireEventuallyFsd(t, dir, fsd{})
+	assert.GreaterOrEqual(t, time.Since(created), w.MinAge)
+
+	assert.ErrorIs(t, stop(), context.Canceled)
+}
+
+func TestWatcherGrace(t *testing.T) {
+	dir := t.TempDir()
+
+	w := &cleardir.Watcher{
+		Grace:    100 * time.Millisecond,
+		Debounce: 5 * time.Millisecond,
+	}
+	stop := startWatcher(t, w, dir)
+
+	// Directories are kept while being filled.
+	require.NoError(t, stdos.Mkdir(path.Join(dir, "new"), 0o755))
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, stdos.WriteFile(path.Join(dir, "new", "keep"), nil, 0o644))
+	time.Sleep(100 * time.Millisecond)
+	requireEventuallyFsd(t, dir, fsd{"new": fsd{"keep": nil}})
+
+	// Empty directories are cleared once their grace period ends.
+	require.NoError(t, stdos.Mkdir(path.Join(dir, "empty"), 0o755))
+	created := time.Now()
+	requireEventuallyFsd(t, dir, fsd{"new": fsd{"keep": nil}})
+	assert.GreaterOrEqual(t, time.Since(created), w.Grace)
+
+	assert.ErrorIs(t, stop(), context.Canceled)
+}
+
+func TestWatcherErrRoot(t *testing.T) {
+	w := &cleardir.Watcher{}
+	err := w.Watch(context.Background(), path.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}